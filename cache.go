@@ -0,0 +1,289 @@
+package goawshelpers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+)
+
+// cacheRefreshWorkers is the maximum amount of keys StartCacheRefresh reads at the same time
+const cacheRefreshWorkers = 4
+
+// ttlCache keeps values for a fixed duration, it is safe for concurrent use
+type ttlCache struct {
+	ttl     time.Duration
+	now     func() time.Time
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+	// seq counts the forget and clear calls, gens holds the seq each parameter was last forgotten at and cleared the one of the last clear
+	// They keep a value read from SSM from being cached when a write forgot it while it was being read (see setSince)
+	// reads counts the running reads by the seq they started at, gens only keeps what the oldest of them needs
+	seq     uint64
+	gens    map[string]uint64
+	cleared uint64
+	reads   map[uint64]int
+}
+
+type cacheEntry struct {
+	value   string
+	expires time.Time
+	// refresh is when the background refresher re-reads the entry, a jittered moment shortly before expires
+	refresh time.Time
+}
+
+func newTTLCache(ttl time.Duration) *ttlCache {
+	return &ttlCache{
+		ttl:     ttl,
+		now:     time.Now,
+		entries: make(map[string]cacheEntry),
+		gens:    make(map[string]uint64),
+		reads:   make(map[uint64]int),
+	}
+}
+
+// get returns a value if it is cached and not expired yet
+func (c *ttlCache) get(name string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[name]
+	if !ok {
+		return "", false
+	}
+
+	if !c.now().Before(entry.expires) {
+		delete(c.entries, name)
+		return "", false
+	}
+
+	return entry.value, true
+}
+
+func (c *ttlCache) set(name, value string) {
+	c.mu.Lock()
+	c.store(name, value)
+	c.mu.Unlock()
+}
+
+// begin registers a read and returns the current generation, to be passed to setSince once the value has been read
+// end must be called with it once the read is over, whether it failed or not
+func (c *ttlCache) begin() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.reads[c.seq]++
+	return c.seq
+}
+
+// end unregisters a read started by begin
+func (c *ttlCache) end(since uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.reads[since]--; c.reads[since] <= 0 {
+		delete(c.reads, since)
+	}
+	c.prune()
+}
+
+// prune drops the generations no running read can be affected by, c.mu must be held when calling it
+func (c *ttlCache) prune() {
+	if len(c.reads) == 0 {
+		if len(c.gens) > 0 {
+			c.gens = make(map[string]uint64)
+		}
+		return
+	}
+
+	oldest := c.seq
+	for since := range c.reads {
+		if since < oldest {
+			oldest = since
+		}
+	}
+
+	for name, gen := range c.gens {
+		if gen <= oldest {
+			delete(c.gens, name)
+		}
+	}
+}
+
+// setSince caches the value unless the parameter was forgotten, or the cache cleared, after begin returned since
+// Otherwise a write landing while the value was being read would get the old value cached again
+func (c *ttlCache) setSince(name, value string, since uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.cleared > since || c.gens[parameterName(name)] > since {
+		return
+	}
+
+	c.store(name, value)
+}
+
+// store caches a value, c.mu must be held when calling it
+func (c *ttlCache) store(name, value string) {
+	expires := c.now().Add(c.ttl)
+
+	// between 10% and 20% of the ttl before expiring, so entries cached together are not refreshed together
+	window := int64(c.ttl / 10)
+	refresh := expires.Add(-time.Duration(window + rand.Int63n(window+1)))
+
+	c.entries[name] = cacheEntry{value: value, expires: expires, refresh: refresh}
+}
+
+// due returns the names of the entries that have to be refreshed, they are not returned again until set
+func (c *ttlCache) due() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	var names []string
+
+	for name, entry := range c.entries {
+		if now.Before(entry.refresh) || !now.Before(entry.expires) {
+			continue
+		}
+
+		names = append(names, name)
+		entry.refresh = entry.expires
+		c.entries[name] = entry
+	}
+
+	return names
+}
+
+// forget removes a parameter together with its cached versions and labels
+func (c *ttlCache) forget(name string) {
+	c.mu.Lock()
+	c.seq++
+	c.gens[parameterName(name)] = c.seq
+	c.prune()
+
+	for cached := range c.entries {
+		if cached == name || strings.HasPrefix(cached, name+":") {
+			delete(c.entries, cached)
+		}
+	}
+	c.mu.Unlock()
+}
+
+func (c *ttlCache) clear() {
+	c.mu.Lock()
+	c.seq++
+	c.cleared = c.seq
+	c.entries = make(map[string]cacheEntry)
+	c.gens = make(map[string]uint64)
+	c.mu.Unlock()
+}
+
+// parameterName strips the :version or :label selector of a cached name
+func parameterName(name string) string {
+	return strings.SplitN(name, ":", 2)[0]
+}
+
+// StartCacheRefresh re-reads the cached values in the background shortly before they expire, with jitter
+// so they don't expire together, which keeps Get from waiting on SSM once a key has been read
+// At most 4 keys are read at the same time. Failed reads are not retried, the value expires as usual
+// It stops once ctx is done or Close is called and fails when CacheTTL is not set
+func (c *SSMConfiguration) StartCacheRefresh(ctx context.Context) error {
+	if c.cache == nil {
+		return errors.New("error starting the cache refresh - CacheTTL is not set")
+	}
+
+	interval := c.cache.ttl / 20
+	if interval <= 0 {
+		interval = c.cache.ttl
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-c.closed:
+				return
+			case <-ticker.C:
+				c.refreshCache(ctx)
+			}
+		}
+	}()
+
+	return nil
+}
+
+// refreshCache re-reads the cache entries that are due
+func (c *SSMConfiguration) refreshCache(ctx context.Context) {
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, cacheRefreshWorkers)
+
+	since := c.cache.begin()
+	defer c.cache.end(since)
+
+	for _, name := range c.cache.due() {
+		wg.Add(1)
+		sem <- struct{}{}
+
+		go func(name string) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			if value, err := c.fetch(ctx, name); err == nil {
+				c.cache.setSince(name, value, since)
+			}
+		}(name)
+	}
+
+	wg.Wait()
+}
+
+// ClearCache forgets every value cached because of CacheTTL
+func (c *SSMConfiguration) ClearCache() {
+	if c.cache != nil {
+		c.cache.clear()
+	}
+}
+
+func (c *SSMConfiguration) uncache(name string) {
+	if c.cache != nil {
+		c.cache.forget(name)
+	}
+}
+
+// Preload reads the whole environment at once (see GetEnvironment) and caches every value for CacheTTL
+// Calling it again refreshes the cache, it fails when CacheTTL is not set
+func (c *SSMConfiguration) Preload() error {
+	return c.PreloadWithContext(context.Background())
+}
+
+// PreloadWithContext is the same as Preload with the ability to pass a context
+func (c *SSMConfiguration) PreloadWithContext(ctx context.Context) error {
+	if c.cache == nil {
+		return errors.New("error preloading parameters - CacheTTL is not set")
+	}
+
+	since := c.cache.begin()
+	defer c.cache.end(since)
+
+	values, err := c.getPathsByPath(ctx, c.prefix(c.env))
+
+	if err != nil {
+		return fmt.Errorf("error preloading parameters - %w", err)
+	}
+
+	for name, value := range values {
+		c.cache.setSince(name, value, since)
+	}
+
+	return nil
+}
@@ -0,0 +1,222 @@
+package goawshelpers
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_SSMConfiguration_CacheTTL(t *testing.T) {
+	config, err := NewSSMConfiguration(SSMConfigurationInit{
+		AwsAccessKey:       "key",
+		AwsSecretAccessKey: "secret",
+		CacheTTL:           time.Minute,
+	})
+	assert.Nil(t, err)
+	assert.NotNil(t, config.cache)
+
+	now := time.Now()
+	client := newFakeSSM()
+	client.seed("/dev/hot", "v1")
+	c := NewSSMConfigurationWithClient(client, "dev", "_")
+	c.cache = newTTLCache(time.Minute)
+	c.cache.now = func() time.Time { return now }
+
+	val, _ := c.Get("hot")
+	assert.Equal(t, "v1", val)
+
+	client.seed("/dev/hot", "v2")
+	client.lastGet = nil
+	val, _ = c.Get("hot")
+	assert.Equal(t, "v1", val)
+	assert.Nil(t, client.lastGet)
+
+	now = now.Add(time.Minute)
+	val, _ = c.Get("hot")
+	assert.Equal(t, "v2", val)
+
+	assert.Nil(t, c.Set("hot", "v3"))
+	val, _ = c.Get("hot")
+	assert.Equal(t, "v3", val)
+
+	client.seed("/dev/hot", "v4")
+	c.ClearCache()
+	val, _ = c.Get("hot")
+	assert.Equal(t, "v4", val)
+
+	assert.Nil(t, c.Delete("hot"))
+	_, err = c.Get("hot")
+	assert.NotNil(t, err)
+}
+
+func Test_SSMConfiguration_Preload(t *testing.T) {
+	client := newFakeSSM()
+	client.seed("/dev/a", "1")
+	client.seed("/dev/b/c", "2")
+	c := NewSSMConfigurationWithClient(client, "dev", "_")
+
+	assert.NotNil(t, c.Preload())
+
+	c.cache = newTTLCache(time.Minute)
+	assert.Nil(t, c.Preload())
+
+	client.lastGet = nil
+	val, err := c.Get("b_c")
+	assert.Nil(t, err)
+	assert.Equal(t, "2", val)
+	assert.Nil(t, client.lastGet)
+
+	client.seed("/dev/a", "changed")
+	assert.Nil(t, c.Preload())
+	val, _ = c.Get("a")
+	assert.Equal(t, "changed", val)
+}
+
+func Test_ttlCache_due(t *testing.T) {
+	now := time.Now()
+	cache := newTTLCache(time.Minute)
+	cache.now = func() time.Time { return now }
+	cache.set("/dev/a", "1")
+
+	assert.Empty(t, cache.due())
+
+	refresh := cache.entries["/dev/a"].refresh
+	assert.WithinDuration(t, now.Add(51*time.Second), refresh, 3*time.Second)
+
+	now = now.Add(55 * time.Second)
+	assert.Equal(t, []string{"/dev/a"}, cache.due())
+	assert.Empty(t, cache.due())
+
+	now = now.Add(5 * time.Second)
+	assert.Empty(t, cache.due())
+}
+
+func Test_ttlCache_gens(t *testing.T) {
+	cache := newTTLCache(time.Minute)
+
+	for i := 0; i < 100; i++ {
+		cache.forget(fmt.Sprintf("/dev/key%d", i))
+	}
+	assert.Empty(t, cache.gens)
+
+	since := cache.begin()
+	cache.forget("/dev/a")
+	cache.setSince("/dev/a", "stale", since)
+	_, ok := cache.get("/dev/a")
+	assert.False(t, ok)
+	assert.Len(t, cache.gens, 1)
+
+	// a read started after the forget can still cache, and once the older one ends the generation is dropped
+	later := cache.begin()
+	cache.end(since)
+	assert.Empty(t, cache.gens)
+	cache.setSince("/dev/a", "fresh", later)
+	cache.end(later)
+
+	val, _ := cache.get("/dev/a")
+	assert.Equal(t, "fresh", val)
+	assert.Empty(t, cache.reads)
+}
+
+func Test_SSMConfiguration_StartCacheRefresh(t *testing.T) {
+	client := newFakeSSM()
+	client.seed("/dev/hot", "v1")
+	c := NewSSMConfigurationWithClient(client, "dev", "_")
+	assert.NotNil(t, c.StartCacheRefresh(context.Background()))
+
+	c.cache = newTTLCache(200 * time.Millisecond)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	assert.Nil(t, c.StartCacheRefresh(ctx))
+
+	val, _ := c.Get("hot")
+	assert.Equal(t, "v1", val)
+
+	client.seed("/dev/hot", "v2")
+	assert.Eventually(t, func() bool {
+		val, ok := c.cache.get("/dev/hot")
+		return ok && val == "v2"
+	}, time.Second, 5*time.Millisecond)
+
+	assert.Nil(t, c.Close())
+}
+
+func Test_SSMConfiguration_WithEnvCacheRefresh(t *testing.T) {
+	client := newFakeSSM()
+	client.seed("/staging/hot", "v1")
+	dev, err := NewSSMConfigurationFromClient(client, SSMConfigurationInit{Env: "dev", CacheTTL: 200 * time.Millisecond, CacheRefresh: true})
+	assert.Nil(t, err)
+	defer dev.Close()
+
+	staging := dev.WithEnv("staging")
+	val, _ := staging.Get("hot")
+	assert.Equal(t, "v1", val)
+
+	client.seed("/staging/hot", "v2")
+	assert.Eventually(t, func() bool {
+		val, ok := staging.cache.get("/staging/hot")
+		return ok && val == "v2"
+	}, time.Second, 5*time.Millisecond)
+
+	assert.Nil(t, staging.Close())
+}
+
+// blockingSSM holds GetParameter calls after they read the value until release is closed
+type blockingSSM struct {
+	*fakeSSM
+	started chan struct{}
+	release chan struct{}
+}
+
+func (b *blockingSSM) GetParameterWithContext(ctx aws.Context, input *ssm.GetParameterInput, opts ...request.Option) (*ssm.GetParameterOutput, error) {
+	out, err := b.fakeSSM.GetParameterWithContext(ctx, input, opts...)
+
+	if b.started != nil {
+		b.started <- struct{}{}
+		<-b.release
+	}
+
+	return out, err
+}
+
+func Test_SSMConfiguration_refreshCacheRacingSet(t *testing.T) {
+	client := &blockingSSM{fakeSSM: newFakeSSM()}
+	client.seed("/dev/hot", "v1")
+	c := NewSSMConfigurationWithClient(client, "dev", "_")
+
+	now := time.Now()
+	c.cache = newTTLCache(time.Minute)
+	c.cache.now = func() time.Time { return now }
+
+	val, _ := c.Get("hot")
+	assert.Equal(t, "v1", val)
+
+	now = now.Add(55 * time.Second)
+	client.started = make(chan struct{})
+	client.release = make(chan struct{})
+
+	done := make(chan struct{})
+	go func() {
+		c.refreshCache(context.Background())
+		close(done)
+	}()
+
+	// the refresh has read v1 when the write lands
+	<-client.started
+	client.started = nil
+	assert.Nil(t, c.Set("hot", "v2"))
+	close(client.release)
+	<-done
+
+	_, ok := c.cache.get("/dev/hot")
+	assert.False(t, ok)
+
+	val, _ = c.Get("hot")
+	assert.Equal(t, "v2", val)
+}
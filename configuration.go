@@ -1,23 +1,60 @@
 package goawshelpers
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"net/http"
 	"os"
+	"sort"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/secretsmanager"
 	"github.com/aws/aws-sdk-go/service/ssm"
 )
 
 const (
-	defaultRegion       = "eu-north-1"
 	defaultKeyDelimitor = "_"
+	// getParametersLimit is the maximum amount of names accepted by a single GetParameters call
+	getParametersLimit = 10
+	// getParametersByPathLimit is the maximum page size accepted by GetParametersByPath
+	getParametersByPathLimit = 10
+	// deleteParametersLimit is the maximum amount of names accepted by a single DeleteParameters call
+	deleteParametersLimit = 10
+	// describeParametersLimit is the maximum page size accepted by DescribeParameters
+	describeParametersLimit = 50
 )
 
+// envMu guards the Values of every EnvironmentConfiguration, as the process environment they mirror is shared
+var envMu sync.RWMutex
+
+// ErrParameterNotFound is returned (wrapped) when the requested key does not exist
+var ErrParameterNotFound = errors.New("parameter not found")
+
+// ErrParameterAlreadyExists is returned (wrapped) by Create when the key already exists
+var ErrParameterAlreadyExists = errors.New("parameter already exists")
+
+// ErrPatternMismatch is returned (wrapped) when a value does not match the AllowedPattern of the parameter
+var ErrPatternMismatch = errors.New("value does not match the allowed pattern")
+
+// ErrKeyCollision is returned (wrapped) when several parameters map to the same key, e.g. /env/a/b and /env/a_b
+var ErrKeyCollision = errors.New("key collision")
+
+// ErrVersionConflict is returned (wrapped) by SetIfVersion when the parameter is not at the expected version
+var ErrVersionConflict = errors.New("version conflict")
+
+// ErrValueMismatch is returned (wrapped) by DeleteIfValue when the parameter does not hold the expected value
+var ErrValueMismatch = errors.New("value mismatch")
+
 // Configuration interface
-// SSMConfiguration follows this interface
+// SSMConfiguration, BiConfiguration and EnvironmentConfiguration follow this interface
 type Configuration interface {
 	Create(key, value string) error
 	Set(key, value string) error
@@ -26,290 +63,2290 @@ type Configuration interface {
 	GetEnvironment() (map[string]string, error)
 }
 
+var (
+	_ Configuration = (*SSMConfiguration)(nil)
+	_ Configuration = (*BiConfiguration)(nil)
+	_ Configuration = (*EnvironmentConfiguration)(nil)
+)
+
+// SSMClient is the subset of the AWS SSM API used by SSMConfiguration
+// *ssm.SSM follows this interface, a mock can be used for testing
+type SSMClient interface {
+	GetParameterWithContext(ctx aws.Context, input *ssm.GetParameterInput, opts ...request.Option) (*ssm.GetParameterOutput, error)
+	GetParametersWithContext(ctx aws.Context, input *ssm.GetParametersInput, opts ...request.Option) (*ssm.GetParametersOutput, error)
+	PutParameterWithContext(ctx aws.Context, input *ssm.PutParameterInput, opts ...request.Option) (*ssm.PutParameterOutput, error)
+	AddTagsToResourceWithContext(ctx aws.Context, input *ssm.AddTagsToResourceInput, opts ...request.Option) (*ssm.AddTagsToResourceOutput, error)
+	DeleteParameterWithContext(ctx aws.Context, input *ssm.DeleteParameterInput, opts ...request.Option) (*ssm.DeleteParameterOutput, error)
+	DeleteParametersWithContext(ctx aws.Context, input *ssm.DeleteParametersInput, opts ...request.Option) (*ssm.DeleteParametersOutput, error)
+	GetParametersByPathPagesWithContext(ctx aws.Context, input *ssm.GetParametersByPathInput, fn func(*ssm.GetParametersByPathOutput, bool) bool, opts ...request.Option) error
+	GetParameterHistoryPagesWithContext(ctx aws.Context, input *ssm.GetParameterHistoryInput, fn func(*ssm.GetParameterHistoryOutput, bool) bool, opts ...request.Option) error
+	DescribeParametersPagesWithContext(ctx aws.Context, input *ssm.DescribeParametersInput, fn func(*ssm.DescribeParametersOutput, bool) bool, opts ...request.Option) error
+	LabelParameterVersionWithContext(ctx aws.Context, input *ssm.LabelParameterVersionInput, opts ...request.Option) (*ssm.LabelParameterVersionOutput, error)
+	UnlabelParameterVersionWithContext(ctx aws.Context, input *ssm.UnlabelParameterVersionInput, opts ...request.Option) (*ssm.UnlabelParameterVersionOutput, error)
+}
+
 // SSMConfiguration provides an easy way to access parameters from AWS Parameter Store
+// All methods are safe for concurrent use
 type SSMConfiguration struct {
-	client       *ssm.SSM
+	client       SSMClient
 	env          string
 	keyDelimitor string
+	secure       bool
+	decrypt      bool
+	kmsKeyID     string
+	tags         map[string]string
+	tier         string
+	namespace    string
+	flat         bool
+	escape       bool
+	normalize    func(string) string
+	dryRun       bool
+	dryRunSink   func(PlannedOperation)
+	logger       Logger
+	cache        *ttlCache
+	// cacheRefresh is whether the cache refresher runs since the configuration was built, see WithEnv
+	cacheRefresh bool
+	// envCaseSensitive keeps the case of the env (and namespace) segment
+	envCaseSensitive bool
+	// httpClient is only set when the session was created by NewSSMConfiguration, see Close
+	httpClient *http.Client
+	closed     chan struct{}
+	closeOnce  *sync.Once
+}
+
+// PutOptions overrides the configuration defaults for a single Set or Create
+type PutOptions struct {
+	// Tags are added on top of the configuration Tags, overriding the ones with the same key
+	Tags map[string]string
+	// Tier overrides the configuration Tier
+	Tier string
+	// Description is shown next to the parameter in the console
+	// SSM clears it when a parameter is overwritten, so SetWithOptions has to pass it on every call to keep it
+	Description string
+	// AllowedPattern is a regular expression SSM checks every future value against, e.g. ^\d{1,5}$
+	// Writes that don't match fail with ErrPatternMismatch
+	AllowedPattern string
+	// Type overrides the parameter type (String, SecureString or StringList) otherwise picked from the configuration
+	Type string
+	// AllowUnknownType skips the validation of Type, so types added to SSM after this package was released can be used
+	AllowUnknownType bool
+	// Policies is a JSON array of parameter policies (Expiration, ExpirationNotification, NoChangeNotification)
+	// They are only supported by the Advanced tier, see SetWithExpiration
+	Policies string
+}
+
+// InvalidParametersError is returned when SSM reports some of the requested keys as invalid or missing
+type InvalidParametersError struct {
+	Keys []string
+}
+
+// ParameterVersion is a single entry of a parameter history
+type ParameterVersion struct {
+	Version          int64
+	Value            string
+	LastModifiedDate time.Time
+	LastModifiedUser string
+	Labels           []string
+}
+
+// Parameter is a value along with its metadata, as returned by GetWithMetadata and DescribeKey
+// Name to DataType come from GetParameter, the remaining fields from DescribeParameters and are only set by DescribeKey
+// Tags are returned by neither, they need ListTagsForResource
+type Parameter struct {
+	// Name is the full parameter name, e.g. /dev/db/host
+	Name             string
+	Value            string
+	Type             string
+	Version          int64
+	LastModifiedDate time.Time
+	ARN              string
+	DataType         string
+	Description      string
+	AllowedPattern   string
+	Tier             string
+	// KmsKeyId is the key SecureString values are encrypted with
+	KmsKeyId string
+	// Policies are the JSON texts of the parameter policies, e.g. an Expiration
+	Policies []string
 }
 
+// KeyErrors aggregates the errors of a multi key operation by key
+type KeyErrors map[string]error
+
 // SSMConfigurationInit helps to initialize SSMConfiguration using a variety of keys
 type SSMConfigurationInit struct {
 	Env                string
 	KeyDelimitor       string
 	AwsAccessKey       string
 	AwsSecretAccessKey string
-	UseEnvParams       bool
-	Region             string
+	// UseEnvParams reads the credentials (including AWS_SESSION_TOKEN) from the standard AWS environment variables
+	// When they are not set the AWS_PROFILE (or default) profile of the shared credentials file is used
+	UseEnvParams bool
+	// Region falls back to AWS_REGION, AWS_DEFAULT_REGION and then the package default (see SetDefaultRegion), which is reported to the Logger
+	Region string
+	// StrictRegion makes NewSSMConfiguration fail instead of using the package default region
+	StrictRegion bool
+	// UseDefaultChain resolves credentials using the SDK default provider chain
+	// (env, shared credentials file, web identity/IRSA, ECS and EC2 instance roles)
+	// The static keys and UseEnvParams are ignored when set
+	UseDefaultChain bool
+	// Secure stores values as SecureString and requests decryption when reading
+	Secure bool
+	// Decrypt requests decryption of SecureString values when reading even if Secure is not set
+	// Leave it off when SecureString parameters are not used to avoid needing the kms:Decrypt permission
+	Decrypt bool
+	// KmsKeyId is the KMS key used to encrypt SecureString values, the aws/ssm key is used when empty
+	KmsKeyId string
+	// MaxRetries is the amount of retries (with exponential backoff and jitter) for failed or throttled calls
+	// The SDK default is used when zero
+	MaxRetries int
+	// RetryBaseDelay is the initial delay the exponential backoff starts from, the SDK default is used when zero
+	// It only applies when MaxRetries is set
+	RetryBaseDelay time.Duration
+	// AssumeRoleArn is a role assumed (through STS) on top of the base credentials before accessing SSM
+	AssumeRoleArn string
+	// ExternalId is passed along when assuming AssumeRoleArn
+	ExternalId string
+	// RoleSessionName is the session name used when assuming AssumeRoleArn, generated by the SDK when empty
+	RoleSessionName string
+	// Endpoint overrides the AWS endpoint, e.g. http://localhost:4566 for LocalStack
+	// Path-style addressing is used when it is set
+	Endpoint string
+	// DisableSSL allows plain http endpoints
+	DisableSSL bool
+	// UseFIPS resolves the FIPS 140-2 endpoints of the region (e.g. ssm-fips.us-east-1.amazonaws.com), it is ignored with Endpoint
+	UseFIPS bool
+	// Tags are added to every parameter written by Set or Create
+	Tags map[string]string
+	// Tier is one of Standard, Advanced or Intelligent-Tiering (default)
+	// Values over 4KB require Advanced, which Intelligent-Tiering picks automatically
+	Tier string
+	// Namespace is inserted between the env and the keys (/env/namespace/key), scoping the configuration to a service
+	Namespace string
+	// FlatNames stores every key as a single leaf under the env (/env/myapp.db.url) instead of translating the delimiter into a hierarchy
+	// GetEnvironment then only reads the parameters directly under the env, prefixes match the start of the key names
+	FlatNames bool
+	// EscapeDelimiter makes a doubled delimiter stand for a literal one, so every parameter name round-trips to a distinct key
+	// With the _ delimiter the key a_b is stored as /env/a/b and a__b as /env/a_b, which would otherwise both read back as a_b
+	// Segments starting or ending with the delimiter (/env/a_/b and /env/a/_b) stay ambiguous and are reported as ErrKeyCollision
+	EscapeDelimiter bool
+	// EnvCaseSensitive keeps the env and the namespace exactly as provided (/Prod/...), by default they are lowercased like the keys
+	EnvCaseSensitive bool
+	// PreserveKeyCase keeps the case of the keys, by default they are lowercased
+	PreserveKeyCase bool
+	// KeyNormalizer is applied to every key before it is turned into a path, it takes precedence over PreserveKeyCase
+	KeyNormalizer func(key string) string
+	// DryRun skips every write (Set, Create, Delete etc.) and reports it instead, reads still reach SSM
+	DryRun bool
+	// DryRunSink receives the operations skipped by DryRun, they are logged without values when it is nil
+	DryRunSink func(PlannedOperation)
+	// Logger is notified about every Get, Set, Create and Delete with its outcome and duration
+	// and about the region when the package default is used
+	Logger Logger
+	// Metrics is called around every SSM API call with its duration and error
+	Metrics MetricsRecorder
+	// CacheTTL caches the values read by Get for the given duration when > 0, see ClearCache
+	// Writes through the same configuration invalidate the cached values right away, other writers are picked up after the TTL
+	CacheTTL time.Duration
+	// CacheRefresh re-reads the cached values in the background before they expire until Close, see StartCacheRefresh
+	CacheRefresh bool
 }
 
 // EnvironmentConfiguration helps with managing environmental variables
+// All methods are safe for concurrent use, Values should not be accessed directly while in use
 type EnvironmentConfiguration struct {
 	UseUpper bool
 	Values   map[string]string
+	// Prefix is prepended to the keys when accessing the process environment, e.g. MYAPP_
+	Prefix string
+	// TreatEmptyAsSet returns variables that are set but empty (e.g. DEBUG=) instead of reporting them as not found
+	TreatEmptyAsSet bool
+	// Defaults are returned by Get when the variable is set neither in the process environment nor in a loaded .env file
+	// BiConfiguration only uses them when the key is found neither in the env nor in ssm
+	Defaults map[string]string
+	// loaded holds the values read by LoadDotEnv, used when the variable is not set in the process environment
+	loaded map[string]string
 }
 
 // BiConfiguration checks both SSM key store and env for variables (SSM first)
 // If SSM configuration not provided it will act as regular EnvironmentConfiguration
+// All methods are safe for concurrent use
 type BiConfiguration struct {
-	ssmConfiguration *SSMConfiguration
+	// PreferEnv checks the env before ssm, allowing local overrides of remote values
+	PreferEnv bool
+	// Logger is notified about every Get with the configuration that was checked (cache, remote or env)
+	Logger Logger
+	// StrictDelete keeps the key in the env when deleting it from ssm fails, so Delete removes it from both or neither
+	StrictDelete bool
+
+	remote           Configuration
 	envConfiguration *EnvironmentConfiguration
 	values           map[string]string
+	mu               sync.RWMutex
 }
 
 // NewSSMConfiguration creates a new instance of SSMConfiguration based on the passed in parameters
 func NewSSMConfiguration(config SSMConfigurationInit) (*SSMConfiguration, error) {
-	var creds *credentials.Credentials
-	region := config.Region
-
-	if config.Region == "" {
-		region = defaultRegion
+	if err := validateTier(config.Tier); err != nil {
+		return nil, err
 	}
 
-	if !config.UseEnvParams {
-		if config.AwsAccessKey == "" && config.AwsSecretAccessKey == "" {
-			return nil, fmt.Errorf("no awsAccessKey and/or awsSecretAccessKey provided")
-		}
+	sess, serviceConfig, err := newSession(config.awsInit())
 
-		creds = credentials.NewStaticCredentials(config.AwsAccessKey, config.AwsSecretAccessKey, "")
-	} else {
-		creds = credentials.NewEnvCredentials()
+	if err != nil {
+		return nil, err
 	}
 
-	session, err := session.NewSession(&aws.Config{
-		Region:      aws.String(region),
-		Credentials: creds,
-	})
+	c, err := NewSSMConfigurationFromClient(ssm.New(sess, serviceConfig), config)
 
 	if err != nil {
-		return nil, fmt.Errorf("error initializing aws session - %w", err)
+		return nil, err
+	}
+	c.httpClient = sess.Config.HTTPClient
+
+	return c, nil
+}
+
+// NewSSMConfigurationFromClient is the same as NewSSMConfiguration using an already built client, e.g. the ssmv2 one
+// The settings of the AWS session (credentials, region, retries, endpoint, FIPS) are ignored as the client owns them
+func NewSSMConfigurationFromClient(client SSMClient, config SSMConfigurationInit) (*SSMConfiguration, error) {
+	if err := validateTier(config.Tier); err != nil {
+		return nil, err
+	}
+
+	c := NewSSMConfigurationWithClient(client, config.Env, config.KeyDelimitor)
+	c.secure = config.Secure
+	c.decrypt = config.Decrypt
+	c.kmsKeyID = config.KmsKeyId
+	c.tags = config.Tags
+	c.tier = config.Tier
+	c.namespace = strings.Trim(config.Namespace, "/")
+	c.flat = config.FlatNames
+	c.escape = config.EscapeDelimiter
+	c.envCaseSensitive = config.EnvCaseSensitive
+
+	if config.PreserveKeyCase {
+		c.normalize = func(key string) string { return key }
+	}
+	if config.KeyNormalizer != nil {
+		c.normalize = config.KeyNormalizer
 	}
 
-	if config.KeyDelimitor == "" {
-		config.KeyDelimitor = defaultKeyDelimitor
+	c.dryRun = config.DryRun
+	c.dryRunSink = config.DryRunSink
+	c.logger = config.Logger
+
+	if config.Metrics != nil {
+		c.client = recordingSSMClient{client: c.client, metrics: config.Metrics}
+	}
+
+	if config.CacheTTL > 0 {
+		c.cache = newTTLCache(config.CacheTTL)
+	}
+
+	if config.CacheRefresh {
+		if err := c.StartCacheRefresh(context.Background()); err != nil {
+			return nil, err
+		}
+		c.cacheRefresh = true
+	}
+
+	return c, nil
+}
+
+func (config SSMConfigurationInit) awsInit() AWSInit {
+	return AWSInit{
+		AwsAccessKey:       config.AwsAccessKey,
+		AwsSecretAccessKey: config.AwsSecretAccessKey,
+		UseEnvParams:       config.UseEnvParams,
+		Region:             config.Region,
+		UseDefaultChain:    config.UseDefaultChain,
+		MaxRetries:         config.MaxRetries,
+		RetryBaseDelay:     config.RetryBaseDelay,
+		AssumeRoleArn:      config.AssumeRoleArn,
+		ExternalId:         config.ExternalId,
+		RoleSessionName:    config.RoleSessionName,
+		Endpoint:           config.Endpoint,
+		DisableSSL:         config.DisableSSL,
+		StrictRegion:       config.StrictRegion,
+		UseFIPS:            config.UseFIPS,
+		Logger:             config.Logger,
+	}
+}
+
+// NewSSMConfigurationFromSession creates a new instance of SSMConfiguration using an already configured aws session
+// Region, credentials, retries etc. are all taken from the session
+func NewSSMConfigurationFromSession(sess *session.Session, env, delimiter string) *SSMConfiguration {
+	return NewSSMConfigurationWithClient(ssm.New(sess), env, delimiter)
+}
+
+// NewSSMConfigurationWithClient creates a new instance of SSMConfiguration using an already built client
+func NewSSMConfigurationWithClient(client SSMClient, env, delimiter string) *SSMConfiguration {
+	if delimiter == "" {
+		delimiter = defaultKeyDelimitor
 	}
 
 	return &SSMConfiguration{
-		client:       ssm.New(session, aws.NewConfig().WithRegion(region)),
-		env:          config.Env,
-		keyDelimitor: config.KeyDelimitor,
-	}, nil
+		client:       client,
+		env:          env,
+		keyDelimitor: delimiter,
+		closed:       make(chan struct{}),
+		closeOnce:    &sync.Once{},
+	}
+}
+
+// WithEnv returns a copy of the configuration scoped to env, sharing the client (and so the session) but not the cache
+// The copy gets its own cache refresher when c was built with CacheRefresh, one started by StartCacheRefresh is not copied
+// Closing either of them doesn't stop the watchers (or the refresher) of the other, only closing c closes the idle connections of the session
+func (c *SSMConfiguration) WithEnv(env string) *SSMConfiguration {
+	scoped := *c
+	scoped.env = env
+	scoped.closed = make(chan struct{})
+	scoped.closeOnce = &sync.Once{}
+	// the transport belongs to c
+	scoped.httpClient = nil
+
+	if c.cache != nil {
+		scoped.cache = newTTLCache(c.cache.ttl)
+	}
+
+	if scoped.cacheRefresh {
+		// can't fail, the cache is set
+		_ = scoped.StartCacheRefresh(context.Background())
+	}
+
+	return &scoped
+}
+
+// Close stops the background Watch and WatchChan pollers and closes the idle HTTP connections of the session
+// Sessions passed to NewSSMConfigurationFromSession belong to the caller and are left alone. Close can be called more than once
+func (c *SSMConfiguration) Close() error {
+	if c.closeOnce == nil {
+		return nil
+	}
+
+	c.closeOnce.Do(func() {
+		close(c.closed)
+
+		if c.httpClient != nil {
+			c.httpClient.CloseIdleConnections()
+		}
+	})
+
+	return nil
 }
 
 // Create creates a new entry in AWS SSM Parameter Store. If the key already exists - an error is returned
 func (c *SSMConfiguration) Create(key, value string) error {
-	if err := c.put(key, value, false); err != nil {
+	return c.CreateWithContext(context.Background(), key, value)
+}
+
+// CreateWithContext is the same as Create with the ability to pass a context
+func (c *SSMConfiguration) CreateWithContext(ctx context.Context, key, value string) error {
+	return c.CreateWithOptions(ctx, key, value, PutOptions{})
+}
+
+// CreateWithOptions is the same as Create with the ability to pass a context and per call options
+func (c *SSMConfiguration) CreateWithOptions(ctx context.Context, key, value string, opts PutOptions) error {
+	start := time.Now()
+	err := c.put(ctx, c.path(key), value, c.parameterType(), false, opts)
+	c.observe("create", key, start, err)
+
+	if err != nil {
 		return fmt.Errorf("error creating a new entry - %w", err)
 	}
 	return nil
 }
 
+// CreateTyped is the same as Create with paramType (String, SecureString or StringList) instead of the configuration default
+func (c *SSMConfiguration) CreateTyped(key, value, paramType string) error {
+	return c.CreateWithOptions(context.Background(), key, value, PutOptions{Type: paramType})
+}
+
 // Set creates or updates an entry in AWS SSM Parameter Store
 func (c *SSMConfiguration) Set(key, value string) error {
-	if err := c.put(key, value, true); err != nil {
+	return c.SetWithContext(context.Background(), key, value)
+}
+
+// SetWithContext is the same as Set with the ability to pass a context
+func (c *SSMConfiguration) SetWithContext(ctx context.Context, key, value string) error {
+	return c.SetWithOptions(ctx, key, value, PutOptions{})
+}
+
+// SetWithOptions is the same as Set with the ability to pass a context and per call options
+func (c *SSMConfiguration) SetWithOptions(ctx context.Context, key, value string, opts PutOptions) error {
+	start := time.Now()
+	err := c.put(ctx, c.path(key), value, c.parameterType(), true, opts)
+	c.observe("set", key, start, err)
+
+	if err != nil {
 		return fmt.Errorf("error setting an entry with key %s - %w", key, err)
 	}
 	return nil
 }
 
-// Delete deletes the remote key
-func (c *SSMConfiguration) Delete(key string) error {
-	_, err := c.client.DeleteParameter(&ssm.DeleteParameterInput{
-		Name: aws.String(convertKeynameToPath(key, c.env, c.keyDelimitor)),
-	})
+// SetTyped is the same as Set with paramType (String, SecureString or StringList) instead of the configuration default
+// e.g. a single SecureString API key next to plain String settings, read it back with GetAndDecrypt unless Decrypt is set
+func (c *SSMConfiguration) SetTyped(key, value, paramType string) error {
+	return c.SetWithOptions(context.Background(), key, value, PutOptions{Type: paramType})
+}
+
+// SetWithExpiration is the same as Set with an Expiration policy making SSM delete the parameter at expireAt
+// Policies need the Advanced tier, which is used unless the configuration has another Tier set, in which case an error is returned
+func (c *SSMConfiguration) SetWithExpiration(key, value string, expireAt time.Time) error {
+	return c.SetWithExpirationWithContext(context.Background(), key, value, expireAt)
+}
+
+// SetWithExpirationWithContext is the same as SetWithExpiration with the ability to pass a context
+func (c *SSMConfiguration) SetWithExpirationWithContext(ctx context.Context, key, value string, expireAt time.Time) error {
+	if !expireAt.After(time.Now()) {
+		return fmt.Errorf("error setting an entry with key %s - expiration %s is not in the future", key, expireAt.Format(time.RFC3339))
+	}
+
+	policies, err := json.Marshal([]parameterPolicy{{
+		Type:       "Expiration",
+		Version:    "1.0",
+		Attributes: map[string]string{"Timestamp": expireAt.UTC().Format(time.RFC3339)},
+	}})
 
 	if err != nil {
-		return fmt.Errorf("error deleting key %s - %w", key, err)
+		return fmt.Errorf("error encoding the expiration policy - %w", err)
 	}
-	return nil
+
+	opts := PutOptions{Policies: string(policies)}
+	if c.tier == "" {
+		opts.Tier = ssm.ParameterTierAdvanced
+	}
+
+	return c.SetWithOptions(ctx, key, value, opts)
 }
 
-// Get returns a key from remote AWS SSM Parameter Store
-func (c *SSMConfiguration) Get(key string) (string, error) {
-	param, err := c.client.GetParameter(&ssm.GetParameterInput{
-		Name: aws.String(convertKeynameToPath(key, c.env, c.keyDelimitor)),
+// parameterPolicy is the JSON form of a parameter policy
+type parameterPolicy struct {
+	Type       string
+	Version    string
+	Attributes map[string]string
+}
+
+// SetIfChanged is the same as Set, but skips the write when the key already has the value
+// This avoids new parameter versions for every unchanged write at the cost of an extra read, which bypasses CacheTTL
+func (c *SSMConfiguration) SetIfChanged(key, value string) (changed bool, err error) {
+	return c.SetIfChangedWithContext(context.Background(), key, value)
+}
+
+// SetIfChangedWithContext is the same as SetIfChanged with the ability to pass a context
+func (c *SSMConfiguration) SetIfChangedWithContext(ctx context.Context, key, value string) (changed bool, err error) {
+	current, err := c.fetch(ctx, c.path(key))
+
+	if err != nil && !errors.Is(err, ErrParameterNotFound) {
+		return false, fmt.Errorf("error retrieving key %s - %w", key, err)
+	}
+
+	if err == nil && current == value {
+		return false, nil
+	}
+
+	if err := c.SetWithContext(ctx, key, value); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// SetIfVersion is the same as Set, but only writes when the key is currently at expectedVersion
+// An expectedVersion of 0 means the key must not exist yet. ErrVersionConflict is returned when the version does not match
+// SSM has no conditional writes, so the version is read first and a concurrent write between the read and the write is not detected
+func (c *SSMConfiguration) SetIfVersion(key, value string, expectedVersion int64) error {
+	return c.SetIfVersionWithContext(context.Background(), key, value, expectedVersion)
+}
+
+// SetIfVersionWithContext is the same as SetIfVersion with the ability to pass a context
+func (c *SSMConfiguration) SetIfVersionWithContext(ctx context.Context, key, value string, expectedVersion int64) error {
+	var version int64
+
+	param, err := c.fetchParameter(ctx, c.path(key))
+
+	switch {
+	case err == nil:
+		version = aws.Int64Value(param.Version)
+	case !errors.Is(err, ErrParameterNotFound):
+		return fmt.Errorf("error retrieving key %s - %w", key, err)
+	}
+
+	if version != expectedVersion {
+		return fmt.Errorf("error setting an entry with key %s - expected version %d, found %d - %w", key, expectedVersion, version, ErrVersionConflict)
+	}
+
+	if expectedVersion == 0 {
+		err := c.CreateWithContext(ctx, key, value)
+
+		if errors.Is(err, ErrParameterAlreadyExists) {
+			return fmt.Errorf("error setting an entry with key %s - created concurrently - %w", key, ErrVersionConflict)
+		}
+
+		return err
+	}
+
+	return c.SetWithContext(ctx, key, value)
+}
+
+// Delete deletes the remote key
+func (c *SSMConfiguration) Delete(key string) error {
+	return c.DeleteWithContext(context.Background(), key)
+}
+
+// DeleteWithContext is the same as Delete with the ability to pass a context
+func (c *SSMConfiguration) DeleteWithContext(ctx context.Context, key string) error {
+	if c.dryRun {
+		c.plan(PlannedOperation{Op: "delete", Name: c.path(key)})
+		return nil
+	}
+
+	start := time.Now()
+	_, err := c.client.DeleteParameterWithContext(ctx, &ssm.DeleteParameterInput{
+		Name: aws.String(c.path(key)),
 	})
+	c.observe("delete", key, start, err)
+	c.uncache(c.path(key))
 
 	if err != nil {
-		return "", fmt.Errorf("error retrieving key %s - %w", key, err)
+		return fmt.Errorf("error deleting key %s - %w", key, translateError(err))
 	}
+	return nil
+}
 
-	return *param.Parameter.Value, nil
+// DeleteIfValue is the same as Delete, but only deletes when the key currently holds expected
+// ErrValueMismatch is returned when the value differs, ErrParameterNotFound when the key does not exist
+// SSM has no conditional deletes, so the value is read first and a concurrent write between the read and the delete is not detected
+func (c *SSMConfiguration) DeleteIfValue(key, expected string) error {
+	return c.DeleteIfValueWithContext(context.Background(), key, expected)
 }
 
-// GetAndDecrypt returns a decrypted key from remote AWS SSM Parameter Store
-func (c *SSMConfiguration) GetAndDecrypt(key string) (string, error) {
-	param, err := c.client.GetParameter(&ssm.GetParameterInput{
-		Name:           aws.String(convertKeynameToPath(key, c.env, c.keyDelimitor)),
-		WithDecryption: aws.Bool(true),
-	})
+// DeleteIfValueWithContext is the same as DeleteIfValue with the ability to pass a context
+func (c *SSMConfiguration) DeleteIfValueWithContext(ctx context.Context, key, expected string) error {
+	// the cache is skipped, a stale value must not allow the delete
+	param, err := c.fetchParameter(ctx, c.path(key))
 
 	if err != nil {
-		return "", fmt.Errorf("error retrieving key %s with decryption - %w", key, err)
+		return fmt.Errorf("error retrieving key %s - %w", key, err)
+	}
+
+	if aws.StringValue(param.Value) != expected {
+		return fmt.Errorf("error deleting key %s - %w", key, ErrValueMismatch)
 	}
 
-	return *param.Parameter.Value, nil
+	return c.DeleteWithContext(ctx, key)
 }
 
-// GetEnvironment returns all the keys existing inside the environment
-// Environment is taken from the *SSMConfiguration struct
-func (c *SSMConfiguration) GetEnvironment() (map[string]string, error) {
-	values := make(map[string]string)
+// DeletePrefix deletes every remote key under the prefix, an empty prefix deletes the whole environment
+// Parameters that could not be deleted are returned as KeyErrors keyed by the parameter name
+func (c *SSMConfiguration) DeletePrefix(prefix string) error {
+	return c.DeletePrefixWithContext(context.Background(), prefix)
+}
 
-	err := c.client.GetParametersByPathPages(&ssm.GetParametersByPathInput{
-		Path:      aws.String(fmt.Sprintf("/%s/", c.env)),
-		Recursive: aws.Bool(true),
+// DeletePrefixWithContext is the same as DeletePrefix with the ability to pass a context
+func (c *SSMConfiguration) DeletePrefixWithContext(ctx context.Context, prefix string) error {
+	path, namePrefix := c.prefix(c.env), ""
+	if prefix != "" {
+		path, namePrefix = c.prefixPath(prefix)
+	}
+
+	var names []string
+
+	err := c.client.GetParametersByPathPagesWithContext(ctx, &ssm.GetParametersByPathInput{
+		Path:       aws.String(path),
+		Recursive:  aws.Bool(!c.flat),
+		MaxResults: aws.Int64(getParametersByPathLimit),
 	}, func(page *ssm.GetParametersByPathOutput, lastPage bool) bool {
 		for _, param := range page.Parameters {
-			key := convertPathToKeyname(*param.Name, c.env, c.keyDelimitor)
-			values[key] = *param.Value
+			if strings.HasPrefix(*param.Name, namePrefix) {
+				names = append(names, *param.Name)
+			}
 		}
 		return !lastPage
 	})
 
 	if err != nil {
-		return nil, fmt.Errorf("error retrieving parameters by environment - %w", err)
+		return fmt.Errorf("error listing parameters under %s - %w", path, err)
 	}
 
-	return values, nil
-}
+	if c.dryRun {
+		for _, name := range names {
+			c.plan(PlannedOperation{Op: "delete", Name: name})
+		}
+		return nil
+	}
 
-func (c *SSMConfiguration) put(key, value string, overwrite bool) error {
-	_, err := c.client.PutParameter(&ssm.PutParameterInput{
-		Name:      aws.String(convertKeynameToPath(key, c.env, c.keyDelimitor)),
-		Value:     aws.String(value),
-		Type:      aws.String("String"),
-		Overwrite: aws.Bool(overwrite),
-	})
+	errs := make(KeyErrors)
+	defer c.ClearCache()
 
-	return err
+	for start := 0; start < len(names); start += deleteParametersLimit {
+		end := start + deleteParametersLimit
+		if end > len(names) {
+			end = len(names)
+		}
+
+		out, err := c.client.DeleteParametersWithContext(ctx, &ssm.DeleteParametersInput{
+			Names: aws.StringSlice(names[start:end]),
+		})
+
+		if err != nil {
+			for _, name := range names[start:end] {
+				errs[name] = err
+			}
+			continue
+		}
+
+		for _, name := range out.InvalidParameters {
+			errs[*name] = ErrParameterNotFound
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("error deleting parameters under %s - %w", path, errs)
+	}
+
+	return nil
 }
 
-// Get returns the key from environment
-func (c *EnvironmentConfiguration) Get(key string) (string, error) {
-	value := os.Getenv(key)
+// Get returns a key from remote AWS SSM Parameter Store
+func (c *SSMConfiguration) Get(key string) (string, error) {
+	return c.GetWithContext(context.Background(), key)
+}
+
+// GetWithContext is the same as Get with the ability to pass a context
+func (c *SSMConfiguration) GetWithContext(ctx context.Context, key string) (string, error) {
+	start := time.Now()
+	value, err := c.get(ctx, c.path(key))
+	c.observe("get", key, start, err)
 
-	if value != "" {
-		c.Values[key] = value
+	if err != nil {
+		return "", fmt.Errorf("error retrieving key %s - %w", key, err)
 	}
 
-	if value == "" {
-		return "", fmt.Errorf("no value with key %s", key)
+	return value, nil
+}
+
+// PathFor returns the parameter name a key is read from and written to, applying the env, namespace, delimiter and case rules
+func (c *SSMConfiguration) PathFor(key string) string {
+	return c.path(key)
+}
+
+// GetForEnv returns a key of another environment, e.g. to compare a value across dev, staging and prod
+// Everything else (delimiter, decryption etc.) is taken from the configuration
+func (c *SSMConfiguration) GetForEnv(env, key string) (string, error) {
+	return c.GetForEnvWithContext(context.Background(), env, key)
+}
+
+// GetForEnvWithContext is the same as GetForEnv with the ability to pass a context
+func (c *SSMConfiguration) GetForEnvWithContext(ctx context.Context, env, key string) (string, error) {
+	start := time.Now()
+	value, err := c.get(ctx, c.pathIn(env, key))
+	c.observe("get", key, start, err)
+
+	if err != nil {
+		return "", fmt.Errorf("error retrieving key %s from environment %s - %w", key, env, err)
 	}
 
 	return value, nil
 }
 
-// Set sets the environmental variable
-func (c *EnvironmentConfiguration) Set(key, value string) error {
-	err := os.Setenv(key, value)
+// GetPath returns a key addressed by its path segments, e.g. GetPath("service", "db_host") reads /env/service/db_host
+// Unlike Get the delimiter is not applied, so segments may contain it
+func (c *SSMConfiguration) GetPath(segments ...string) (string, error) {
+	return c.GetPathWithContext(context.Background(), segments...)
+}
+
+// GetPathWithContext is the same as GetPath with the ability to pass a context
+func (c *SSMConfiguration) GetPathWithContext(ctx context.Context, segments ...string) (string, error) {
+	name, err := c.segmentsPath(segments)
 
 	if err != nil {
-		return fmt.Errorf("error setting environmental variable %s - %w", key, err)
+		return "", err
 	}
 
-	c.Values[key] = value
+	value, err := c.get(ctx, name)
 
-	return nil
+	if err != nil {
+		return "", fmt.Errorf("error retrieving path %s - %w", name, err)
+	}
+
+	return value, nil
 }
 
-// GetEnvironment returns all previously used variables
-func (c *EnvironmentConfiguration) GetEnvironment() (map[string]string, error) {
-	return c.Values, nil
+// SetPath creates or updates a key addressed by its path segments, see GetPath
+func (c *SSMConfiguration) SetPath(value string, segments ...string) error {
+	return c.SetPathWithContext(context.Background(), value, segments...)
 }
 
-// Delete destroys the variable from the environment and "cache"
-func (c *EnvironmentConfiguration) Delete(key string) error {
-	err := os.Unsetenv(key)
+// SetPathWithContext is the same as SetPath with the ability to pass a context
+func (c *SSMConfiguration) SetPathWithContext(ctx context.Context, value string, segments ...string) error {
+	name, err := c.segmentsPath(segments)
 
 	if err != nil {
-		return fmt.Errorf("error unsetting environmental variable %s - %w", key, err)
+		return err
+	}
+
+	if err := c.put(ctx, name, value, c.parameterType(), true, PutOptions{}); err != nil {
+		return fmt.Errorf("error setting an entry with path %s - %w", name, err)
 	}
-	delete(c.Values, key)
 
 	return nil
 }
 
-// NewBiConfiguration returns a new instance of dual configuration
-func NewBiConfiguration(env EnvironmentConfiguration, ssmConfig *SSMConfigurationInit) (*BiConfiguration, error) {
-	config := &BiConfiguration{
-		envConfiguration: &env,
+func (c *SSMConfiguration) get(ctx context.Context, name string) (string, error) {
+	if err := ValidateKey(strings.SplitN(name, ":", 2)[0]); err != nil {
+		return "", err
 	}
 
-	if ssmConfig != nil {
-		ssmConfiguration, err := NewSSMConfiguration(*ssmConfig)
+	var since uint64
 
-		if err != nil {
-			return nil, fmt.Errorf("error creating SSM configuration - %w", err)
+	if c.cache != nil {
+		if value, ok := c.cache.get(name); ok {
+			return value, nil
 		}
-		config.ssmConfiguration = ssmConfiguration
+		since = c.cache.begin()
+		defer c.cache.end(since)
 	}
 
-	return config, nil
-}
+	value, err := c.fetch(ctx, name)
 
-// Get returns a value from configurations
-// First it checkks the ssm (if applicable) and then the env
-func (c *BiConfiguration) Get(key string) (string, error) {
-	if val, ok := c.values[key]; ok {
-		return val, nil
+	if err != nil {
+		return "", err
 	}
 
-	if c.ssmConfiguration != nil {
-		val, err := c.ssmConfiguration.Get(key)
-
-		if err == nil {
-			return val, nil
-		}
+	if c.cache != nil {
+		c.cache.setSince(name, value, since)
 	}
 
-	val, err := c.envConfiguration.Get(key)
+	return value, nil
+}
+
+// fetch reads a parameter value from SSM, bypassing the cache
+func (c *SSMConfiguration) fetch(ctx context.Context, name string) (string, error) {
+	param, err := c.fetchParameter(ctx, name)
 
 	if err != nil {
 		return "", err
 	}
 
-	return val, nil
+	return *param.Value, nil
 }
 
-// GetEnvironment returns all the variables from the ssm based on environment and also the loaded ones from local env
-func (c *BiConfiguration) GetEnvironment() (map[string]string, error) {
-	values, _ := c.envConfiguration.GetEnvironment()
+func (c *SSMConfiguration) fetchParameter(ctx context.Context, name string) (*ssm.Parameter, error) {
+	return c.getParameter(ctx, name, c.decryption())
+}
 
-	if c.ssmConfiguration != nil {
-		sValues, err := c.ssmConfiguration.GetEnvironment()
+// getParameter calls GetParameter, a response without a parameter or value is returned as an error
+func (c *SSMConfiguration) getParameter(ctx context.Context, name string, decrypt bool) (*ssm.Parameter, error) {
+	out, err := c.client.GetParameterWithContext(ctx, &ssm.GetParameterInput{
+		Name:           aws.String(name),
+		WithDecryption: aws.Bool(decrypt),
+	})
 
-		if err != nil {
-			return nil, fmt.Errorf("error retrieving environment - %w", err)
-		}
+	if err != nil {
+		return nil, translateError(err)
+	}
 
-		for k, v := range sValues {
-			values[k] = v
-		}
+	if out == nil || out.Parameter == nil || out.Parameter.Value == nil {
+		return nil, fmt.Errorf("no value returned for parameter %s", name)
 	}
 
-	return values, nil
+	return out.Parameter, nil
 }
 
-// Delete deletes the keys from both configurations
-func (c *BiConfiguration) Delete(key string) error {
-	if c.ssmConfiguration != nil {
-		c.ssmConfiguration.Delete(key)
+// GetWithMetadata returns a key together with its version, type etc. from remote AWS SSM Parameter Store
+// Like Get it accepts a :version or :label selector, the cache is never used
+func (c *SSMConfiguration) GetWithMetadata(key string) (Parameter, error) {
+	return c.GetWithMetadataWithContext(context.Background(), key)
+}
+
+// GetWithMetadataWithContext is the same as GetWithMetadata with the ability to pass a context
+func (c *SSMConfiguration) GetWithMetadataWithContext(ctx context.Context, key string) (Parameter, error) {
+	name := c.path(key)
+
+	if err := ValidateKey(strings.SplitN(name, ":", 2)[0]); err != nil {
+		return Parameter{}, err
 	}
-	c.envConfiguration.Delete(key)
 
-	delete(c.values, key)
-	delete(c.envConfiguration.Values, key)
+	param, err := c.fetchParameter(ctx, name)
 
-	return nil
+	if err != nil {
+		return Parameter{}, fmt.Errorf("error retrieving key %s - %w", key, err)
+	}
+
+	return newParameter(param), nil
 }
 
-func convertKeynameToPath(key, env, delimiter string) string {
-	return strings.ToLower(fmt.Sprintf("/%s/%s", env, strings.ReplaceAll(key, delimiter, "/")))
+// DescribeKey is the same as GetWithMetadata but also returns the description, allowed pattern, tier, KMS key and policies
+// It takes an extra DescribeParameters call, everything needed to recreate the parameter elsewhere is returned except its tags
+// With a :version or :label selector the value is the selected one, the extra details are always the current ones
+func (c *SSMConfiguration) DescribeKey(key string) (Parameter, error) {
+	return c.DescribeKeyWithContext(context.Background(), key)
 }
 
-func convertPathToKeyname(path, env, delimiter string) string {
-	key := strings.Replace(path, fmt.Sprintf("/%s/", env), "", 1)
-	key = strings.ReplaceAll(key, "/", delimiter)
-	return strings.ToLower(key)
+// DescribeKeyWithContext is the same as DescribeKey with the ability to pass a context
+func (c *SSMConfiguration) DescribeKeyWithContext(ctx context.Context, key string) (Parameter, error) {
+	name := c.path(key)
+	base := parameterName(name)
+
+	if err := ValidateKey(base); err != nil {
+		return Parameter{}, err
+	}
+
+	param, err := c.fetchParameter(ctx, name)
+
+	if err != nil {
+		return Parameter{}, fmt.Errorf("error retrieving key %s - %w", key, err)
+	}
+
+	var metadata *ssm.ParameterMetadata
+
+	err = c.client.DescribeParametersPagesWithContext(ctx, &ssm.DescribeParametersInput{
+		ParameterFilters: []*ssm.ParameterStringFilter{{
+			Key:    aws.String("Name"),
+			Option: aws.String("Equals"),
+			Values: aws.StringSlice([]string{base}),
+		}},
+	}, func(page *ssm.DescribeParametersOutput, lastPage bool) bool {
+		if len(page.Parameters) > 0 {
+			metadata = page.Parameters[0]
+			return false
+		}
+		return !lastPage
+	})
+
+	if err != nil {
+		return Parameter{}, fmt.Errorf("error describing key %s - %w", key, err)
+	}
+
+	// deleted between the two calls
+	if metadata == nil {
+		return Parameter{}, fmt.Errorf("error describing key %s - %w", key, ErrParameterNotFound)
+	}
+
+	described := newParameter(param)
+	described.Description = aws.StringValue(metadata.Description)
+	described.AllowedPattern = aws.StringValue(metadata.AllowedPattern)
+	described.Tier = aws.StringValue(metadata.Tier)
+	described.KmsKeyId = aws.StringValue(metadata.KeyId)
+
+	for _, policy := range metadata.Policies {
+		described.Policies = append(described.Policies, aws.StringValue(policy.PolicyText))
+	}
+
+	return described, nil
+}
+
+func newParameter(param *ssm.Parameter) Parameter {
+	return Parameter{
+		Name:             aws.StringValue(param.Name),
+		Value:            aws.StringValue(param.Value),
+		Type:             aws.StringValue(param.Type),
+		Version:          aws.Int64Value(param.Version),
+		LastModifiedDate: aws.TimeValue(param.LastModifiedDate),
+		ARN:              aws.StringValue(param.ARN),
+		DataType:         aws.StringValue(param.DataType),
+	}
+}
+
+// GetVersion returns a specific version of a key from remote AWS SSM Parameter Store
+func (c *SSMConfiguration) GetVersion(key string, version int64) (string, error) {
+	return c.GetWithContext(context.Background(), fmt.Sprintf("%s:%d", key, version))
+}
+
+// GetByLabel returns the version of a key the label points to from remote AWS SSM Parameter Store
+func (c *SSMConfiguration) GetByLabel(key, label string) (string, error) {
+	return c.GetWithContext(context.Background(), fmt.Sprintf("%s:%s", key, label))
+}
+
+// GetHistory returns every version of a key from remote AWS SSM Parameter Store, newest first
+func (c *SSMConfiguration) GetHistory(key string) ([]ParameterVersion, error) {
+	return c.GetHistoryWithContext(context.Background(), key)
+}
+
+// GetHistoryWithContext is the same as GetHistory with the ability to pass a context
+func (c *SSMConfiguration) GetHistoryWithContext(ctx context.Context, key string) ([]ParameterVersion, error) {
+	var history []ParameterVersion
+
+	err := c.client.GetParameterHistoryPagesWithContext(ctx, &ssm.GetParameterHistoryInput{
+		Name:           aws.String(c.path(key)),
+		WithDecryption: aws.Bool(c.decryption()),
+	}, func(page *ssm.GetParameterHistoryOutput, lastPage bool) bool {
+		for _, param := range page.Parameters {
+			history = append(history, ParameterVersion{
+				Version:          aws.Int64Value(param.Version),
+				Value:            aws.StringValue(param.Value),
+				LastModifiedDate: aws.TimeValue(param.LastModifiedDate),
+				LastModifiedUser: aws.StringValue(param.LastModifiedUser),
+				Labels:           aws.StringValueSlice(param.Labels),
+			})
+		}
+		return !lastPage
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving history of key %s - %w", key, translateError(err))
+	}
+
+	// SSM returns the oldest version first
+	for i, j := 0, len(history)-1; i < j; i, j = i+1, j-1 {
+		history[i], history[j] = history[j], history[i]
+	}
+
+	return history, nil
+}
+
+// GetAndDecrypt returns a decrypted key from remote AWS SSM Parameter Store
+func (c *SSMConfiguration) GetAndDecrypt(key string) (string, error) {
+	return c.GetAndDecryptWithContext(context.Background(), key)
+}
+
+// GetAndDecryptWithContext is the same as GetAndDecrypt with the ability to pass a context
+func (c *SSMConfiguration) GetAndDecryptWithContext(ctx context.Context, key string) (string, error) {
+	param, err := c.getParameter(ctx, c.path(key), true)
+
+	if err != nil {
+		return "", fmt.Errorf("error retrieving key %s with decryption - %w", key, err)
+	}
+
+	return *param.Value, nil
+}
+
+// SetList creates or updates a StringList entry in AWS SSM Parameter Store
+// SSM stores lists as a single comma separated value, so the values can not contain commas and the list can not be empty
+func (c *SSMConfiguration) SetList(key string, values []string) error {
+	return c.SetListWithContext(context.Background(), key, values)
+}
+
+// SetListWithContext is the same as SetList with the ability to pass a context
+func (c *SSMConfiguration) SetListWithContext(ctx context.Context, key string, values []string) error {
+	if len(values) == 0 {
+		return fmt.Errorf("error setting a list with key %s - SSM does not support empty StringList values", key)
+	}
+
+	for _, value := range values {
+		if strings.Contains(value, ",") {
+			return fmt.Errorf("error setting a list with key %s - value %q contains a comma which SSM uses as the StringList separator", key, value)
+		}
+	}
+
+	if err := c.put(ctx, c.path(key), strings.Join(values, ","), ssm.ParameterTypeStringList, true, PutOptions{}); err != nil {
+		return fmt.Errorf("error setting a list with key %s - %w", key, err)
+	}
+	return nil
+}
+
+// GetList returns a StringList key from remote AWS SSM Parameter Store split into its values
+// Decryption is never requested as lists can't be SecureString. Values are trimmed and an empty list gives an empty slice
+func (c *SSMConfiguration) GetList(key string) ([]string, error) {
+	return c.GetListWithContext(context.Background(), key)
+}
+
+// GetListWithContext is the same as GetList with the ability to pass a context
+func (c *SSMConfiguration) GetListWithContext(ctx context.Context, key string) ([]string, error) {
+	name := c.path(key)
+
+	if err := ValidateKey(strings.SplitN(name, ":", 2)[0]); err != nil {
+		return nil, err
+	}
+
+	param, err := c.getParameter(ctx, name, false)
+
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving key %s - %w", key, err)
+	}
+
+	value := strings.TrimSpace(*param.Value)
+
+	if value == "" {
+		return []string{}, nil
+	}
+
+	values := strings.Split(value, ",")
+	for i := range values {
+		values[i] = strings.TrimSpace(values[i])
+	}
+
+	return values, nil
+}
+
+// GetMany returns multiple keys from remote AWS SSM Parameter Store using as few calls as possible
+// Keys that do not exist are returned inside an *InvalidParametersError along with the found values
+func (c *SSMConfiguration) GetMany(keys []string) (map[string]string, error) {
+	return c.GetManyWithContext(context.Background(), keys)
+}
+
+// GetManyWithContext is the same as GetMany with the ability to pass a context
+func (c *SSMConfiguration) GetManyWithContext(ctx context.Context, keys []string) (map[string]string, error) {
+	values := make(map[string]string)
+	pathKeys := make(map[string][]string)
+	var paths []string
+
+	for _, key := range keys {
+		path := c.path(key)
+		if _, ok := pathKeys[path]; !ok {
+			paths = append(paths, path)
+		}
+		pathKeys[path] = append(pathKeys[path], key)
+	}
+
+	var invalid []string
+
+	for start := 0; start < len(paths); start += getParametersLimit {
+		end := start + getParametersLimit
+		if end > len(paths) {
+			end = len(paths)
+		}
+
+		out, err := c.client.GetParametersWithContext(ctx, &ssm.GetParametersInput{
+			Names:          aws.StringSlice(paths[start:end]),
+			WithDecryption: aws.Bool(c.decryption()),
+		})
+
+		if err != nil {
+			return nil, fmt.Errorf("error retrieving multiple keys - %w", err)
+		}
+
+		for _, param := range out.Parameters {
+			for _, key := range pathKeys[*param.Name] {
+				values[key] = aws.StringValue(param.Value)
+			}
+		}
+
+		for _, name := range out.InvalidParameters {
+			invalid = append(invalid, pathKeys[*name]...)
+		}
+	}
+
+	if len(invalid) > 0 {
+		return values, &InvalidParametersError{Keys: invalid}
+	}
+
+	return values, nil
+}
+
+// GetEnvironment returns all the keys existing inside the environment
+// Environment is taken from the *SSMConfiguration struct
+// Parameters are requested in the largest pages SSM allows (10 per call)
+// SecureString values are decrypted when the configuration is Secure or Decrypt is set, same as in Get
+func (c *SSMConfiguration) GetEnvironment() (map[string]string, error) {
+	return c.GetEnvironmentWithContext(context.Background())
+}
+
+// GetEnvironmentWithContext is the same as GetEnvironment with the ability to pass a context
+func (c *SSMConfiguration) GetEnvironmentWithContext(ctx context.Context) (map[string]string, error) {
+	values, err := c.getByPath(ctx, c.prefix(c.env))
+
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving parameters by environment - %w", err)
+	}
+
+	return values, nil
+}
+
+// GetEnvironmentByPrefix is the same as GetEnvironment, limited to the keys under prefix (e.g. database reads database_*)
+// The returned keys still include the prefix, an empty map is returned when there are none
+func (c *SSMConfiguration) GetEnvironmentByPrefix(prefix string) (map[string]string, error) {
+	return c.GetEnvironmentByPrefixWithContext(context.Background(), prefix)
+}
+
+// GetEnvironmentByPrefixWithContext is the same as GetEnvironmentByPrefix with the ability to pass a context
+func (c *SSMConfiguration) GetEnvironmentByPrefixWithContext(ctx context.Context, prefix string) (map[string]string, error) {
+	path, namePrefix := c.prefixPath(prefix)
+	params, err := c.getPathsByPath(ctx, path)
+
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving parameters under %s - %w", path, err)
+	}
+
+	values, err := c.keysFromPaths(filterNames(params, namePrefix))
+
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving parameters under %s - %w", namePrefix, err)
+	}
+
+	return values, nil
+}
+
+// GetAll is the same as GetEnvironment but one failing parameter (e.g. no kms:Decrypt on a restricted key) doesn't fail the whole read
+// The readable keys are always returned, the failed ones are reported as KeyErrors along with them
+// Callers can decide whether partial results are acceptable, e.g. with errors.As(err, &KeyErrors{})
+func (c *SSMConfiguration) GetAll() (map[string]string, error) {
+	return c.GetAllWithContext(context.Background())
+}
+
+// GetAllWithContext is the same as GetAll with the ability to pass a context
+func (c *SSMConfiguration) GetAllWithContext(ctx context.Context) (map[string]string, error) {
+	names, err := c.listNames(ctx)
+
+	if err != nil {
+		return nil, fmt.Errorf("error listing parameters by environment - %w", err)
+	}
+
+	params := make(map[string]string, len(names))
+	errs := make(KeyErrors)
+
+	for start := 0; start < len(names); start += getParametersLimit {
+		end := start + getParametersLimit
+		if end > len(names) {
+			end = len(names)
+		}
+
+		batch, failed := c.getBatch(ctx, names[start:end])
+
+		for name, value := range batch {
+			params[name] = value
+		}
+
+		for name, err := range failed {
+			errs[c.keyname(name)] = err
+		}
+	}
+
+	values, err := c.keysFromPaths(params)
+
+	if collisions, ok := err.(KeyErrors); ok {
+		// keep the keys that don't collide, same as for the unreadable ones
+		for key, err := range collisions {
+			errs[key] = err
+		}
+
+		values = make(map[string]string, len(params))
+		for name, value := range params {
+			if _, ok := collisions[c.keyname(name)]; !ok {
+				values[c.keyname(name)] = value
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		return values, errs
+	}
+
+	return values, nil
+}
+
+// getBatch reads up to getParametersLimit parameters at once, keyed by parameter name
+// When the batch call fails the parameters are read one by one so the error is attributed to the failing ones only
+func (c *SSMConfiguration) getBatch(ctx context.Context, names []string) (map[string]string, map[string]error) {
+	values := make(map[string]string, len(names))
+	errs := make(map[string]error)
+
+	out, err := c.client.GetParametersWithContext(ctx, &ssm.GetParametersInput{
+		Names:          aws.StringSlice(names),
+		WithDecryption: aws.Bool(c.decryption()),
+	})
+
+	if err == nil {
+		for _, param := range out.Parameters {
+			values[*param.Name] = aws.StringValue(param.Value)
+		}
+		// deleted between listing and reading
+		for _, name := range out.InvalidParameters {
+			errs[*name] = ErrParameterNotFound
+		}
+		return values, errs
+	}
+
+	for _, name := range names {
+		param, err := c.getParameter(ctx, name, c.decryption())
+
+		if err != nil {
+			errs[name] = err
+			continue
+		}
+
+		values[name] = *param.Value
+	}
+
+	return values, errs
+}
+
+// GetEnvironmentDetailed is the same as GetEnvironment but keeps the type, version and last modification date of every parameter
+// It is meant for tools copying parameters around, which have to write them back with the same type
+func (c *SSMConfiguration) GetEnvironmentDetailed() (map[string]Parameter, error) {
+	return c.GetEnvironmentDetailedWithContext(context.Background())
+}
+
+// GetEnvironmentDetailedWithContext is the same as GetEnvironmentDetailed with the ability to pass a context
+func (c *SSMConfiguration) GetEnvironmentDetailedWithContext(ctx context.Context) (map[string]Parameter, error) {
+	params, err := c.getDetailedByPath(ctx, c.prefix(c.env))
+
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving parameters by environment - %w", err)
+	}
+
+	return params, nil
+}
+
+// getByPath returns every parameter value under path keyed by key name, see getDetailedByPath
+func (c *SSMConfiguration) getByPath(ctx context.Context, path string) (map[string]string, error) {
+	params, err := c.getDetailedByPath(ctx, path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]string, len(params))
+	for key, param := range params {
+		values[key] = param.Value
+	}
+
+	return values, nil
+}
+
+// getDetailedByPath returns every parameter under path keyed by key name
+// Parameters that map to the same key (e.g. /env/a/b and /env/a_b) are reported as KeyErrors wrapping ErrKeyCollision
+func (c *SSMConfiguration) getDetailedByPath(ctx context.Context, path string) (map[string]Parameter, error) {
+	params, err := c.getParametersByPath(ctx, path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(params))
+	for name := range params {
+		names = append(names, name)
+	}
+
+	keys, err := c.keysByName(names)
+
+	if err != nil {
+		return nil, err
+	}
+
+	detailed := make(map[string]Parameter, len(params))
+	for name, key := range keys {
+		detailed[key] = params[name]
+	}
+
+	return detailed, nil
+}
+
+// keysFromPaths converts parameter names into key names, see getDetailedByPath
+func (c *SSMConfiguration) keysFromPaths(params map[string]string) (map[string]string, error) {
+	names := make([]string, 0, len(params))
+	for name := range params {
+		names = append(names, name)
+	}
+
+	keys, err := c.keysByName(names)
+
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]string, len(params))
+	for name, key := range keys {
+		values[key] = params[name]
+	}
+
+	return values, nil
+}
+
+// keysByName returns the key name of every parameter name, names mapping to the same key are reported as KeyErrors
+func (c *SSMConfiguration) keysByName(names []string) (map[string]string, error) {
+	sort.Strings(names)
+
+	keys := make(map[string]string, len(names))
+	sources := make(map[string]string, len(names))
+	errs := make(KeyErrors)
+
+	for _, name := range names {
+		key := c.keyname(name)
+
+		if source, ok := sources[key]; ok {
+			errs[key] = fmt.Errorf("%s and %s - %w", source, name, ErrKeyCollision)
+			continue
+		}
+
+		keys[name] = key
+		sources[key] = name
+	}
+
+	if len(errs) > 0 {
+		return nil, errs
+	}
+
+	return keys, nil
+}
+
+// filterNames returns the parameters whose name starts with namePrefix
+func filterNames(params map[string]string, namePrefix string) map[string]string {
+	filtered := make(map[string]string, len(params))
+	for name, value := range params {
+		if strings.HasPrefix(name, namePrefix) {
+			filtered[name] = value
+		}
+	}
+	return filtered
+}
+
+// GetEnvironmentPaths is the same as GetEnvironment but keeps the full parameter names as keys
+// Unlike GetEnvironment it can't fail because of parameters mapping to the same key
+func (c *SSMConfiguration) GetEnvironmentPaths() (map[string]string, error) {
+	return c.GetEnvironmentPathsWithContext(context.Background())
+}
+
+// GetEnvironmentPathsWithContext is the same as GetEnvironmentPaths with the ability to pass a context
+func (c *SSMConfiguration) GetEnvironmentPathsWithContext(ctx context.Context) (map[string]string, error) {
+	values, err := c.getPathsByPath(ctx, c.prefix(c.env))
+
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving parameters by environment - %w", err)
+	}
+
+	return values, nil
+}
+
+// getPathsByPath returns every parameter value under path keyed by parameter name
+func (c *SSMConfiguration) getPathsByPath(ctx context.Context, path string) (map[string]string, error) {
+	params, err := c.getParametersByPath(ctx, path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]string, len(params))
+	for name, param := range params {
+		values[name] = param.Value
+	}
+
+	return values, nil
+}
+
+// getParametersByPath returns every parameter under path keyed by parameter name
+func (c *SSMConfiguration) getParametersByPath(ctx context.Context, path string) (map[string]Parameter, error) {
+	params := make(map[string]Parameter)
+
+	err := c.client.GetParametersByPathPagesWithContext(ctx, &ssm.GetParametersByPathInput{
+		Path:           aws.String(path),
+		Recursive:      aws.Bool(!c.flat),
+		WithDecryption: aws.Bool(c.decryption()),
+		MaxResults:     aws.Int64(getParametersByPathLimit),
+	}, func(page *ssm.GetParametersByPathOutput, lastPage bool) bool {
+		for _, param := range page.Parameters {
+			params[*param.Name] = newParameter(param)
+		}
+		return !lastPage
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return params, nil
+}
+
+// Keys returns the sorted names of all the keys of the environment without their values
+// Only metadata is read, so kms:Decrypt is not needed even for SecureString parameters
+func (c *SSMConfiguration) Keys() ([]string, error) {
+	return c.KeysWithContext(context.Background())
+}
+
+// KeysWithContext is the same as Keys with the ability to pass a context
+func (c *SSMConfiguration) KeysWithContext(ctx context.Context) ([]string, error) {
+	names, err := c.listNames(ctx)
+
+	if err != nil {
+		return nil, fmt.Errorf("error listing keys - %w", err)
+	}
+
+	keys := make([]string, 0, len(names))
+	for _, name := range names {
+		keys = append(keys, c.keyname(name))
+	}
+	sort.Strings(keys)
+
+	return keys, nil
+}
+
+// listNames returns the names of all the parameters of the environment, only metadata is read
+func (c *SSMConfiguration) listNames(ctx context.Context) ([]string, error) {
+	var names []string
+
+	option := "Recursive"
+	if c.flat {
+		option = "OneLevel"
+	}
+
+	err := c.client.DescribeParametersPagesWithContext(ctx, &ssm.DescribeParametersInput{
+		ParameterFilters: []*ssm.ParameterStringFilter{{
+			Key:    aws.String("Path"),
+			Option: aws.String(option),
+			Values: aws.StringSlice([]string{strings.TrimSuffix(c.prefix(c.env), "/")}),
+		}},
+		MaxResults: aws.Int64(describeParametersLimit),
+	}, func(page *ssm.DescribeParametersOutput, lastPage bool) bool {
+		for _, param := range page.Parameters {
+			names = append(names, *param.Name)
+		}
+		return !lastPage
+	})
+
+	return names, err
+}
+
+// ListEnvironments returns the sorted distinct environments (first path segments) found in SSM Parameter Store
+// Parameters without a path, e.g. plain names, are ignored. An empty slice is returned when there are none
+func (c *SSMConfiguration) ListEnvironments() ([]string, error) {
+	return c.ListEnvironmentsWithContext(context.Background())
+}
+
+// ListEnvironmentsWithContext is the same as ListEnvironments with the ability to pass a context
+func (c *SSMConfiguration) ListEnvironmentsWithContext(ctx context.Context) ([]string, error) {
+	seen := make(map[string]bool)
+
+	err := c.client.DescribeParametersPagesWithContext(ctx, &ssm.DescribeParametersInput{
+		MaxResults: aws.Int64(describeParametersLimit),
+	}, func(page *ssm.DescribeParametersOutput, lastPage bool) bool {
+		for _, param := range page.Parameters {
+			segments := strings.SplitN(aws.StringValue(param.Name), "/", 3)
+			if len(segments) == 3 && segments[0] == "" && segments[1] != "" {
+				seen[segments[1]] = true
+			}
+		}
+		return !lastPage
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("error listing environments - %w", err)
+	}
+
+	envs := make([]string, 0, len(seen))
+	for env := range seen {
+		envs = append(envs, env)
+	}
+	sort.Strings(envs)
+
+	return envs, nil
+}
+
+func (c *SSMConfiguration) put(ctx context.Context, name, value, paramType string, overwrite bool, opts PutOptions) error {
+	if err := ValidateKey(name); err != nil {
+		return err
+	}
+
+	tier := c.tier
+	if opts.Tier != "" {
+		tier = opts.Tier
+	}
+
+	if err := validateTier(tier); err != nil {
+		return err
+	}
+
+	if tier == "" {
+		tier = ssm.ParameterTierIntelligentTiering
+	}
+
+	if opts.Policies != "" && tier != ssm.ParameterTierAdvanced {
+		return fmt.Errorf("parameter policies require the %s tier, got %s", ssm.ParameterTierAdvanced, tier)
+	}
+
+	if opts.Type != "" {
+		paramType = opts.Type
+	}
+
+	if err := validateType(paramType, opts.AllowUnknownType); err != nil {
+		return err
+	}
+
+	if c.dryRun {
+		op := "create"
+		if overwrite {
+			op = "set"
+		}
+		c.plan(PlannedOperation{Op: op, Name: name, Value: value, Type: paramType})
+		return nil
+	}
+
+	input := &ssm.PutParameterInput{
+		Name:      aws.String(name),
+		Value:     aws.String(value),
+		Type:      aws.String(paramType),
+		Overwrite: aws.Bool(overwrite),
+		Tier:      aws.String(tier),
+	}
+
+	if opts.Description != "" {
+		input.Description = aws.String(opts.Description)
+	}
+
+	if opts.AllowedPattern != "" {
+		input.AllowedPattern = aws.String(opts.AllowedPattern)
+	}
+
+	if opts.Policies != "" {
+		input.Policies = aws.String(opts.Policies)
+	}
+
+	if paramType == ssm.ParameterTypeSecureString && c.kmsKeyID != "" {
+		input.KeyId = aws.String(c.kmsKeyID)
+	}
+
+	tags := c.mergeTags(opts.Tags)
+
+	// SSM refuses Tags together with Overwrite, so existing parameters are tagged separately
+	if !overwrite {
+		input.Tags = tags
+	}
+
+	if _, err := c.client.PutParameterWithContext(ctx, input); err != nil {
+		return translateError(err)
+	}
+	c.uncache(name)
+
+	if overwrite && len(tags) > 0 {
+		_, err := c.client.AddTagsToResourceWithContext(ctx, &ssm.AddTagsToResourceInput{
+			ResourceId:   aws.String(name),
+			ResourceType: aws.String(ssm.ResourceTypeForTaggingParameter),
+			Tags:         tags,
+		})
+
+		if err != nil {
+			return fmt.Errorf("error tagging parameter - %w", err)
+		}
+	}
+
+	return nil
+}
+
+// mergeTags combines the configuration tags with the per call ones into sorted SSM tags
+func (c *SSMConfiguration) mergeTags(extra map[string]string) []*ssm.Tag {
+	merged := make(map[string]string, len(c.tags)+len(extra))
+	for k, v := range c.tags {
+		merged[k] = v
+	}
+	for k, v := range extra {
+		merged[k] = v
+	}
+
+	keys := make([]string, 0, len(merged))
+	for k := range merged {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var tags []*ssm.Tag
+	for _, k := range keys {
+		tags = append(tags, &ssm.Tag{Key: aws.String(k), Value: aws.String(merged[k])})
+	}
+
+	return tags
+}
+
+// scope returns the env joined with the namespace, it takes the place of the env in the parameter names
+func (c *SSMConfiguration) scope(env string) string {
+	if c.namespace == "" {
+		return env
+	}
+	return env + "/" + c.namespace
+}
+
+// prefix returns the path all the parameters of env live under, the env is lowercased unless EnvCaseSensitive is set
+func (c *SSMConfiguration) prefix(env string) string {
+	if c.envCaseSensitive {
+		return "/" + c.scope(env) + "/"
+	}
+	return envPrefix(c.scope(env))
+}
+
+// path converts a key into the parameter name
+func (c *SSMConfiguration) path(key string) string {
+	return c.pathIn(c.env, key)
+}
+
+// pathIn converts a key into the parameter name inside env
+func (c *SSMConfiguration) pathIn(env, key string) string {
+	return keyToPath(key, c.prefix(env), c.delimiter(), c.escaping(), c.normalizer())
+}
+
+// keyname converts a parameter name back into a key
+func (c *SSMConfiguration) keyname(path string) string {
+	return pathToKey(path, c.prefix(c.env), c.delimiter(), c.escaping(), c.normalizer())
+}
+
+// escaping reports whether doubled delimiters are escaped, keys are never translated in flat mode
+func (c *SSMConfiguration) escaping() bool {
+	return c.escape && !c.flat
+}
+
+// delimiter returns the delimiter translated into path separators, in flat mode keys are kept as they are
+func (c *SSMConfiguration) delimiter() string {
+	if c.flat {
+		return "/"
+	}
+	return c.keyDelimitor
+}
+
+// prefixPath returns the path to list for the keys starting with prefix along with the name prefix they all share
+// In flat mode the whole env is listed and filtered by name
+func (c *SSMConfiguration) prefixPath(prefix string) (path, namePrefix string) {
+	if c.flat {
+		return c.prefix(c.env), c.path(prefix)
+	}
+	path = c.path(strings.TrimSuffix(prefix, c.keyDelimitor)) + "/"
+	return path, path
+}
+
+// segmentsPath joins path segments into a parameter name without applying the delimiter
+func (c *SSMConfiguration) segmentsPath(segments []string) (string, error) {
+	if len(segments) == 0 {
+		return "", errors.New("no path segments provided")
+	}
+
+	normalize := c.normalizer()
+	normalized := make([]string, len(segments))
+
+	for i, segment := range segments {
+		if segment == "" {
+			return "", fmt.Errorf("path segment %d is empty", i)
+		}
+		normalized[i] = normalize(segment)
+	}
+
+	return c.prefix(c.env) + strings.Join(normalized, "/"), nil
+}
+
+// normalizer returns the key normalization, keys are lowercased by default
+func (c *SSMConfiguration) normalizer() func(string) string {
+	if c.normalize != nil {
+		return c.normalize
+	}
+	return strings.ToLower
+}
+
+func (c *SSMConfiguration) decryption() bool {
+	return c.secure || c.decrypt
+}
+
+func (c *SSMConfiguration) parameterType() string {
+	if c.secure {
+		return ssm.ParameterTypeSecureString
+	}
+	return ssm.ParameterTypeString
+}
+
+// Error lists the invalid keys
+func (e *InvalidParametersError) Error() string {
+	return fmt.Sprintf("invalid parameters: %s", strings.Join(e.Keys, ", "))
+}
+
+// Unwrap allows matching the error against ErrParameterNotFound
+func (e *InvalidParametersError) Unwrap() error {
+	return ErrParameterNotFound
+}
+
+// NewEnvironmentConfiguration returns a new instance of EnvironmentConfiguration with an initialized cache
+func NewEnvironmentConfiguration(useUpper bool) *EnvironmentConfiguration {
+	return &EnvironmentConfiguration{
+		UseUpper: useUpper,
+		Values:   make(map[string]string),
+	}
+}
+
+// Error lists every failed key with its error in sorted key order
+func (e KeyErrors) Error() string {
+	keys := make([]string, 0, len(e))
+	for key := range e {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	messages := make([]string, 0, len(keys))
+	for _, key := range keys {
+		messages = append(messages, fmt.Sprintf("%s: %v", key, e[key]))
+	}
+
+	return fmt.Sprintf("%d keys failed - %s", len(e), strings.Join(messages, "; "))
+}
+
+// Is reports whether any of the key errors matches target, e.g. errors.Is(err, ErrKeyCollision)
+func (e KeyErrors) Is(target error) bool {
+	for _, err := range e {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// NewEnvironmentConfigurationFromOS returns a new instance of EnvironmentConfiguration with every variable
+// of the process environment starting with prefix already loaded, the prefix is stripped from the keys
+// An empty prefix loads the whole environment
+func NewEnvironmentConfigurationFromOS(prefix string) *EnvironmentConfiguration {
+	c := NewEnvironmentConfiguration(false)
+	c.Prefix = prefix
+
+	for _, pair := range os.Environ() {
+		i := strings.Index(pair, "=")
+
+		if i <= 0 || !strings.HasPrefix(pair[:i], prefix) {
+			continue
+		}
+
+		c.Values[strings.TrimPrefix(pair[:i], prefix)] = pair[i+1:]
+	}
+
+	return c
+}
+
+// Get returns the key from environment
+// Values loaded through LoadDotEnv are returned when the variable is not set in the process environment, then Defaults
+// Values records the variables that were returned, defaults are not recorded
+func (c *EnvironmentConfiguration) Get(key string) (string, error) {
+	value, err := c.get(key)
+
+	if errors.Is(err, ErrParameterNotFound) {
+		if value, ok := c.defaultValue(key); ok {
+			return value, nil
+		}
+	}
+
+	return value, err
+}
+
+// defaultValue returns the default of the key, a default is set on purpose, even when empty
+func (c *EnvironmentConfiguration) defaultValue(key string) (string, bool) {
+	envMu.RLock()
+	defer envMu.RUnlock()
+
+	value, ok := c.Defaults[key]
+	return value, ok
+}
+
+// get is Get without the Defaults
+func (c *EnvironmentConfiguration) get(key string) (string, error) {
+	value, ok := os.LookupEnv(c.Prefix + key)
+	found := ok && (value != "" || c.TreatEmptyAsSet)
+
+	if !found {
+		envMu.RLock()
+		value, ok = c.loaded[key]
+		found = ok && (value != "" || c.TreatEmptyAsSet)
+		envMu.RUnlock()
+	}
+
+	if !found {
+		return "", fmt.Errorf("no value with key %s - %w", key, ErrParameterNotFound)
+	}
+
+	envMu.Lock()
+	c.init()
+	c.Values[key] = value
+	envMu.Unlock()
+
+	return value, nil
+}
+
+// Create sets the environmental variable, an error is returned if it is already set in the process environment (even empty)
+// Values loaded through LoadDotEnv and Defaults don't count as set
+func (c *EnvironmentConfiguration) Create(key, value string) error {
+	if _, ok := os.LookupEnv(c.Prefix + key); ok {
+		return fmt.Errorf("error creating a new entry with key %s - %w", key, ErrParameterAlreadyExists)
+	}
+
+	return c.Set(key, value)
+}
+
+// Set sets the environmental variable
+func (c *EnvironmentConfiguration) Set(key, value string) error {
+	err := os.Setenv(c.Prefix+key, value)
+
+	if err != nil {
+		return fmt.Errorf("error setting environmental variable %s - %w", c.Prefix+key, err)
+	}
+
+	envMu.Lock()
+	c.init()
+	c.Values[key] = value
+	envMu.Unlock()
+
+	return nil
+}
+
+// GetEnvironment returns a copy of all previously used variables
+func (c *EnvironmentConfiguration) GetEnvironment() (map[string]string, error) {
+	envMu.RLock()
+	defer envMu.RUnlock()
+
+	values := make(map[string]string, len(c.Values))
+	for k, v := range c.Values {
+		values[k] = v
+	}
+
+	return values, nil
+}
+
+// has reports whether the key is set in the process environment (even empty), cached or loaded from a .env file
+func (c *EnvironmentConfiguration) has(key string) bool {
+	if _, ok := os.LookupEnv(c.Prefix + key); ok {
+		return true
+	}
+
+	envMu.RLock()
+	defer envMu.RUnlock()
+
+	_, cached := c.Values[key]
+	_, loaded := c.loaded[key]
+
+	return cached || loaded
+}
+
+// Delete destroys the variable from the environment and "cache"
+func (c *EnvironmentConfiguration) Delete(key string) error {
+	err := os.Unsetenv(c.Prefix + key)
+
+	if err != nil {
+		return fmt.Errorf("error unsetting environmental variable %s - %w", c.Prefix+key, err)
+	}
+
+	envMu.Lock()
+	delete(c.Values, key)
+	delete(c.loaded, key)
+	envMu.Unlock()
+
+	return nil
+}
+
+// init makes sure the cache exists when the struct was created without NewEnvironmentConfiguration
+// envMu must be held when calling it
+func (c *EnvironmentConfiguration) init() {
+	if c.Values == nil {
+		c.Values = make(map[string]string)
+	}
+}
+
+// NewBiConfiguration returns a new instance of dual configuration
+func NewBiConfiguration(env EnvironmentConfiguration, ssmConfig *SSMConfigurationInit) (*BiConfiguration, error) {
+	config := &BiConfiguration{
+		envConfiguration: &env,
+		values:           make(map[string]string),
+	}
+
+	if ssmConfig != nil {
+		ssmConfiguration, err := NewSSMConfiguration(*ssmConfig)
+
+		if err != nil {
+			return nil, fmt.Errorf("error creating SSM configuration - %w", err)
+		}
+		config.remote = ssmConfiguration
+	}
+
+	return config, nil
+}
+
+// NewBiConfigurationWith returns a new instance of dual configuration using already built configurations
+// remote takes the place of SSM and can be any Configuration (e.g. SecretsManagerConfiguration), or nil
+// A new EnvironmentConfiguration is used when env is nil
+func NewBiConfigurationWith(env *EnvironmentConfiguration, remote Configuration) *BiConfiguration {
+	if env == nil {
+		env = NewEnvironmentConfiguration(false)
+	}
+
+	return &BiConfiguration{
+		remote:           remote,
+		envConfiguration: env,
+		values:           make(map[string]string),
+	}
+}
+
+// Get returns a value from configurations
+// Found values are cached, so subsequent calls for the same key do not reach ssm or the env
+// First it checkks the ssm (if applicable) and then the env, or the other way around with PreferEnv
+// The next one is only checked when the key was not found, other errors are returned as is
+func (c *BiConfiguration) Get(key string) (string, error) {
+	c.mu.RLock()
+	val, ok := c.values[key]
+	c.mu.RUnlock()
+
+	if ok {
+		if c.Logger != nil {
+			c.Logger.Log(Event{Op: "get", Key: key, Source: "cache"})
+		}
+		return val, nil
+	}
+
+	val, err := lookup(key, c.lookupOrder()...)
+
+	// the env defaults only apply when neither of the configurations has the key, they are not cached
+	if errors.Is(err, ErrParameterNotFound) {
+		if val, ok := c.envConfiguration.defaultValue(key); ok {
+			if c.Logger != nil {
+				c.Logger.Log(Event{Op: "get", Key: key, Source: "default"})
+			}
+			return val, nil
+		}
+	}
+
+	if err != nil {
+		return "", err
+	}
+	c.cache(key, val)
+
+	return val, nil
+}
+
+// getter is the read part of Configuration, which EnvironmentConfiguration also follows
+type getter interface {
+	Get(key string) (string, error)
+}
+
+// envWithoutDefaults reads the env like EnvironmentConfiguration.Get without the Defaults
+type envWithoutDefaults struct {
+	env *EnvironmentConfiguration
+}
+
+func (g envWithoutDefaults) Get(key string) (string, error) {
+	return g.env.get(key)
+}
+
+// lookupOrder returns the configurations in the order Get checks them
+func (c *BiConfiguration) lookupOrder() []getter {
+	var env, remote getter = envWithoutDefaults{c.envConfiguration}, c.remote
+
+	if c.Logger != nil {
+		env = observedGetter{getter: env, source: "env", logger: c.Logger}
+		remote = observedGetter{getter: remote, source: "remote", logger: c.Logger}
+	}
+
+	if c.remote == nil {
+		return []getter{env}
+	}
+
+	if c.PreferEnv {
+		return []getter{env, remote}
+	}
+
+	return []getter{remote, env}
+}
+
+// ClearCache forgets all the values cached by Get, Set and Create
+func (c *BiConfiguration) ClearCache() {
+	c.mu.Lock()
+	c.values = make(map[string]string)
+	c.mu.Unlock()
+}
+
+// cache stores the value of a written key, the keys read from the same remote parameter are read again
+func (c *BiConfiguration) cache(key, value string) {
+	c.mu.Lock()
+	c.evict(key)
+	c.values[key] = value
+	c.mu.Unlock()
+}
+
+func (c *BiConfiguration) uncache(key string) {
+	c.mu.Lock()
+	c.evict(key)
+	c.mu.Unlock()
+}
+
+// evict drops the key, together with the cached keys read from the same remote parameter (e.g. DB_HOST and db_host)
+// c.mu must be held
+func (c *BiConfiguration) evict(key string) {
+	delete(c.values, key)
+
+	name, ok := c.remoteName(key)
+	if !ok {
+		return
+	}
+
+	for cached := range c.values {
+		if cachedName, _ := c.remoteName(cached); cachedName == name {
+			delete(c.values, cached)
+		}
+	}
+}
+
+// pathResolver is implemented by the remote configurations which read several keys from the same parameter
+type pathResolver interface {
+	PathFor(key string) string
+}
+
+// remoteName returns the remote parameter a key is read from, when the remote configuration tells
+func (c *BiConfiguration) remoteName(key string) (string, bool) {
+	resolver, ok := c.remote.(pathResolver)
+	if !ok {
+		return "", false
+	}
+
+	return resolver.PathFor(key), true
+}
+
+// Refresh replaces the cache with a fresh read of the whole environment (e.g. after a secret rotation)
+// The cache is left untouched if the environment can not be read
+// Concurrent Get calls keep being served from the old cache until the new one is swapped in
+func (c *BiConfiguration) Refresh() error {
+	envValues, _ := c.envConfiguration.GetEnvironment()
+	var remoteValues map[string]string
+
+	if c.remote != nil {
+		var err error
+
+		if remoteValues, err = c.remote.GetEnvironment(); err != nil {
+			return fmt.Errorf("error refreshing configuration - %w", err)
+		}
+	}
+
+	values := make(map[string]string, len(envValues)+len(remoteValues))
+	byName := make(map[string]string, len(remoteValues))
+
+	for key, value := range remoteValues {
+		values[key] = value
+
+		if name, ok := c.remoteName(key); ok {
+			byName[name] = value
+		}
+	}
+
+	// like in Get the env only wins with PreferEnv, also over a remote key read from the same parameter (DB_HOST and db_host)
+	for key, value := range envValues {
+		if !c.PreferEnv {
+			if _, ok := values[key]; ok {
+				continue
+			}
+
+			if name, ok := c.remoteName(key); ok {
+				if remote, ok := byName[name]; ok {
+					values[key] = remote
+					continue
+				}
+			}
+		}
+
+		values[key] = value
+	}
+
+	c.mu.Lock()
+	c.values = values
+	c.mu.Unlock()
+
+	return nil
+}
+
+// RefreshKey drops the cached value of the key and reads it again
+func (c *BiConfiguration) RefreshKey(key string) error {
+	c.uncache(key)
+
+	if _, err := c.Get(key); err != nil {
+		return fmt.Errorf("error refreshing key %s - %w", key, err)
+	}
+
+	return nil
+}
+
+// Create creates the key in ssm (if applicable) and sets it in the env
+// An error is returned if the key already exists, in the process environment when there is no ssm (see EnvironmentConfiguration.Create)
+func (c *BiConfiguration) Create(key, value string) error {
+	var err error
+
+	if c.remote != nil {
+		if err = c.remote.Create(key, value); err == nil {
+			err = c.envConfiguration.Set(key, value)
+		}
+	} else {
+		err = c.envConfiguration.Create(key, value)
+	}
+
+	if err != nil {
+		return err
+	}
+	c.cache(key, value)
+
+	return nil
+}
+
+// Set sets the key in ssm (if applicable) and in the env
+func (c *BiConfiguration) Set(key, value string) error {
+	if c.remote != nil {
+		if err := c.remote.Set(key, value); err != nil {
+			return err
+		}
+	}
+
+	if err := c.envConfiguration.Set(key, value); err != nil {
+		return err
+	}
+	c.cache(key, value)
+
+	return nil
+}
+
+// GetEnvironment returns all the variables from the ssm based on environment and also the loaded ones from local env
+// When a key exists in both the ssm value wins, unless PreferEnv is set
+func (c *BiConfiguration) GetEnvironment() (map[string]string, error) {
+	values, _ := c.envConfiguration.GetEnvironment()
+
+	if c.remote != nil {
+		rValues, err := c.remote.GetEnvironment()
+
+		if err != nil {
+			return nil, fmt.Errorf("error retrieving environment - %w", err)
+		}
+
+		for k, v := range rValues {
+			if _, ok := values[k]; ok && c.PreferEnv {
+				continue
+			}
+			values[k] = v
+		}
+	}
+
+	return values, nil
+}
+
+// Delete deletes the keys from both configurations
+// Failures are returned as KeyErrors keyed by the configuration (remote or env), the env is still cleared
+// when ssm fails unless StrictDelete is set. A key missing from ssm is only reported (as ErrParameterNotFound)
+// when it was not in the env either, keys provided by the env alone (e.g. OS variables) are deleted without error
+func (c *BiConfiguration) Delete(key string) error {
+	errs := make(KeyErrors)
+	inEnv := c.envConfiguration.has(key)
+
+	if c.remote != nil {
+		err := c.remote.Delete(key)
+
+		if errors.Is(err, ErrParameterNotFound) && inEnv {
+			err = nil
+		}
+
+		if err != nil {
+			errs["remote"] = err
+
+			if c.StrictDelete {
+				return fmt.Errorf("error deleting key %s - %w", key, errs)
+			}
+		}
+	}
+
+	if err := c.envConfiguration.Delete(key); err != nil {
+		errs["env"] = err
+	}
+
+	c.uncache(key)
+
+	if len(errs) > 0 {
+		return fmt.Errorf("error deleting key %s - %w", key, errs)
+	}
+
+	return nil
+}
+
+// convertKeynameToPath keeps a trailing :version or :label selector untouched
+func convertKeynameToPath(key, env, delimiter string) string {
+	return keyToPath(key, envPrefix(env), delimiter, false, strings.ToLower)
+}
+
+func convertPathToKeyname(path, env, delimiter string) string {
+	return pathToKey(path, envPrefix(env), delimiter, false, strings.ToLower)
+}
+
+// keyToPath is convertKeynameToPath with a custom key normalization, prefix is the env path (see envPrefix)
+// When escape is set a doubled delimiter is kept as a literal delimiter instead of becoming a path separator
+func keyToPath(key, prefix, delimiter string, escape bool, normalize func(string) string) string {
+	selector := ""
+	if i := strings.Index(key, ":"); i >= 0 {
+		key, selector = key[:i], key[i:]
+	}
+
+	key = normalize(key)
+	if !escape {
+		return prefix + strings.ReplaceAll(key, delimiter, "/") + selector
+	}
+
+	parts := strings.Split(key, delimiter+delimiter)
+	for i, part := range parts {
+		parts[i] = strings.ReplaceAll(part, delimiter, "/")
+	}
+	return prefix + strings.Join(parts, delimiter) + selector
+}
+
+// pathToKey is convertPathToKeyname with a custom key normalization
+// Only a leading prefix is stripped, it is matched regardless of case
+func pathToKey(path, prefix, delimiter string, escape bool, normalize func(string) string) string {
+	key := path
+	if len(key) >= len(prefix) && strings.EqualFold(key[:len(prefix)], prefix) {
+		key = key[len(prefix):]
+	}
+	if escape {
+		key = strings.ReplaceAll(key, delimiter, delimiter+delimiter)
+	}
+	key = strings.ReplaceAll(key, "/", delimiter)
+	return normalize(key)
+}
+
+// envPrefix returns the path all the parameters of env live under
+func envPrefix(env string) string {
+	return "/" + strings.ToLower(env) + "/"
+}
+
+// validateType returns an error if the type is not one of the SSM parameter types, unless allowUnknown is set
+func validateType(paramType string, allowUnknown bool) error {
+	if allowUnknown {
+		return nil
+	}
+
+	for _, known := range ssm.ParameterType_Values() {
+		if paramType == known {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("invalid parameter type %q, expected one of %s", paramType, strings.Join(ssm.ParameterType_Values(), ", "))
+}
+
+// validateTier returns an error if the tier is not empty nor one of the SSM tiers
+func validateTier(tier string) error {
+	if tier == "" {
+		return nil
+	}
+
+	for _, known := range ssm.ParameterTier_Values() {
+		if tier == known {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("invalid parameter tier %q, expected one of %s", tier, strings.Join(ssm.ParameterTier_Values(), ", "))
+}
+
+// translateError converts known AWS error codes into the package errors, wrapping the AWS error (see awsError)
+func translateError(err error) error {
+	var aerr awserr.Error
+	if !errors.As(err, &aerr) {
+		return err
+	}
+
+	switch aerr.Code() {
+	case ssm.ErrCodeParameterNotFound, secretsmanager.ErrCodeResourceNotFoundException:
+		return &awsError{sentinel: ErrParameterNotFound, err: err}
+	case ssm.ErrCodeParameterAlreadyExists, secretsmanager.ErrCodeResourceExistsException:
+		return &awsError{sentinel: ErrParameterAlreadyExists, err: err}
+	case ssm.ErrCodeParameterPatternMismatchException:
+		// the message names the pattern
+		return &awsError{sentinel: ErrPatternMismatch, err: err}
+	}
+	return err
+}
+
+// awsError is a sentinel error (e.g. ErrParameterNotFound) keeping the AWS error it was translated from
+// errors.Is matches the sentinel, while errors.As still reaches the awserr.Error with its message and request ID
+type awsError struct {
+	sentinel error
+	err      error
+}
+
+func (e *awsError) Error() string {
+	return e.sentinel.Error() + " - " + e.err.Error()
+}
+
+func (e *awsError) Unwrap() error {
+	return e.err
+}
+
+func (e *awsError) Is(target error) bool {
+	return target == e.sentinel
 }
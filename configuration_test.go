@@ -1,9 +1,22 @@
 package goawshelpers
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ssm"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -13,11 +26,104 @@ func Test_convertKeynameToPath(t *testing.T) {
 	assert.Equal(t, "/dev/hello/world", path)
 }
 
+func Test_convertKeynameToPath_selector(t *testing.T) {
+	assert.Equal(t, "/dev/hello/world:Stable", convertKeynameToPath("HELLO_WORLD:Stable", "dev", "_"))
+	assert.Equal(t, "/dev/hello:3", convertKeynameToPath("hello:3", "dev", "_"))
+}
+
 func Test_convertPathToKeyname(t *testing.T) {
 	path := "/dev/hello/world"
 	assert.Equal(t, convertPathToKeyname(path, "dev", "."), "hello.world")
 }
 
+func Test_convertPathToKeyname_env(t *testing.T) {
+	assert.Equal(t, "hello_world", convertPathToKeyname("/dev/hello/world", "Dev", "_"))
+	assert.Equal(t, "app_dev_key", convertPathToKeyname("/dev/app/dev/key", "dev", "_"))
+	assert.Equal(t, "other_key", convertPathToKeyname("other/key", "dev", "_"))
+}
+
+func Test_convertPathToKeyname_roundTrip(t *testing.T) {
+	for _, env := range []string{"dev", "Dev", "PROD"} {
+		for _, key := range []string{"hello", "hello_world", "dev_key", "a_dev_b"} {
+			assert.Equal(t, key, convertPathToKeyname(convertKeynameToPath(key, env, "_"), env, "_"), env+" "+key)
+		}
+	}
+}
+
+func Test_keyToPath_escape(t *testing.T) {
+	cases := map[string]string{
+		"a_b":      "/dev/a/b",
+		"a__b":     "/dev/a_b",
+		"a__b_c":   "/dev/a_b/c",
+		"x_a__b_c": "/dev/x/a_b/c",
+	}
+
+	for key, path := range cases {
+		assert.Equal(t, path, keyToPath(key, "/dev/", "_", true, strings.ToLower), key)
+		assert.Equal(t, key, pathToKey(path, "/dev/", "_", true, strings.ToLower), path)
+	}
+
+	assert.Equal(t, "/dev/a_b:3", keyToPath("a__b:3", "/dev/", "_", true, strings.ToLower))
+}
+
+func Test_SSMConfiguration_PathFor(t *testing.T) {
+	c := NewSSMConfigurationWithClient(newFakeSSM(), "Dev", "_")
+	assert.Equal(t, "/dev/db/host", c.PathFor("DB_HOST"))
+	assert.Equal(t, "/dev/db/host:3", c.PathFor("db_host:3"))
+
+	c.namespace = "billing"
+	c.escape = true
+	assert.Equal(t, "/dev/billing/db_host/name", c.PathFor("db__host_name"))
+
+	c.flat = true
+	assert.Equal(t, "/dev/billing/myapp.db_url", c.PathFor("myapp.db_url"))
+}
+
+func Test_SSMConfiguration_EnvCaseSensitive(t *testing.T) {
+	client := newFakeSSM()
+	client.seed("/Prod/db/host", "db.prod")
+	client.seed("/prod/db/host", "other")
+	c := NewSSMConfigurationWithClient(client, "Prod", "_")
+	c.envCaseSensitive = true
+
+	val, err := c.Get("DB_HOST")
+	assert.Nil(t, err)
+	assert.Equal(t, "db.prod", val)
+
+	assert.Nil(t, c.Set("API_KEY", "secret"))
+	assert.Equal(t, "secret", *client.params["/Prod/api/key"].Value)
+
+	values, err := c.GetEnvironment()
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"db_host": "db.prod", "api_key": "secret"}, values)
+
+	c.envCaseSensitive = false
+	val, err = c.Get("db_host")
+	assert.Nil(t, err)
+	assert.Equal(t, "other", val)
+}
+
+func Test_SSMConfiguration_EscapeDelimiter(t *testing.T) {
+	client := newFakeSSM()
+	client.seed("/dev/a/b", "hierarchical")
+	client.seed("/dev/a_b", "flat")
+	c := NewSSMConfigurationWithClient(client, "dev", "_")
+
+	_, err := c.GetEnvironment()
+	assert.True(t, errors.Is(err, ErrKeyCollision))
+
+	c.escape = true
+	values, err := c.GetEnvironment()
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"a_b": "hierarchical", "a__b": "flat"}, values)
+
+	for key, value := range values {
+		val, err := c.Get(key)
+		assert.Nil(t, err)
+		assert.Equal(t, value, val)
+	}
+}
+
 func Test_SSMConfiguration(t *testing.T) {
 	config, err := NewSSMConfiguration(SSMConfigurationInit{
 		UseEnvParams: false,
@@ -41,9 +147,1378 @@ func Test_BiConfiguration(t *testing.T) {
 	val, err := b.Get("nonexistingkeyshouldgohere")
 
 	assert.NotNil(t, err)
-	assert.Equal(t, fmt.Sprint(err), "no value with key nonexistingkeyshouldgohere")
+	assert.Equal(t, fmt.Sprint(err), "no value with key nonexistingkeyshouldgohere - parameter not found")
+	assert.True(t, errors.Is(err, ErrParameterNotFound))
 	assert.Equal(t, val, "")
 
 	err = b.Delete("random key that does nothing")
 	assert.Nil(t, err)
 }
+
+func Test_parameterType(t *testing.T) {
+	c := &SSMConfiguration{}
+	assert.Equal(t, "String", c.parameterType())
+
+	c.secure = true
+	assert.Equal(t, "SecureString", c.parameterType())
+}
+
+func Test_translateError(t *testing.T) {
+	err := translateError(awserr.NewRequestFailure(awserr.New(ssm.ErrCodeParameterNotFound, "not found", nil), 400, "req-1"))
+	assert.True(t, errors.Is(err, ErrParameterNotFound))
+	assert.False(t, errors.Is(err, ErrParameterAlreadyExists))
+	assert.Equal(t, "parameter not found - ParameterNotFound: not found\n\tstatus code: 400, request id: req-1", err.Error())
+
+	var failure awserr.RequestFailure
+	assert.True(t, errors.As(err, &failure))
+	assert.Equal(t, "req-1", failure.RequestID())
+
+	other := awserr.New("AccessDeniedException", "denied", nil)
+	assert.Equal(t, other, translateError(other))
+}
+
+func Test_SSMConfigurationWithClient(t *testing.T) {
+	client := newFakeSSM()
+	c := NewSSMConfigurationWithClient(client, "dev", "")
+
+	assert.Nil(t, c.Set("DATABASE_URL", "postgres://localhost"))
+	assert.Equal(t, "/dev/database/url", *client.lastPut.Name)
+	assert.Equal(t, ssm.ParameterTypeString, *client.lastPut.Type)
+	assert.True(t, *client.lastPut.Overwrite)
+
+	val, err := c.Get("DATABASE_URL")
+	assert.Nil(t, err)
+	assert.Equal(t, "postgres://localhost", val)
+
+	_, err = c.Get("missing")
+	assert.True(t, errors.Is(err, ErrParameterNotFound))
+	assert.Contains(t, fmt.Sprint(err), "error retrieving key missing")
+
+	client.seed("/staging/other", "value")
+	values, err := c.GetEnvironment()
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"database_url": "postgres://localhost"}, values)
+
+	assert.Nil(t, c.Delete("DATABASE_URL"))
+	_, err = c.Get("DATABASE_URL")
+	assert.True(t, errors.Is(err, ErrParameterNotFound))
+}
+
+func Test_SSMConfiguration_GetMany(t *testing.T) {
+	client := newFakeSSM()
+	for i := 0; i < 15; i++ {
+		client.seed(fmt.Sprintf("/dev/key/%d", i), fmt.Sprint(i))
+	}
+	c := NewSSMConfigurationWithClient(client, "dev", "_")
+
+	keys := []string{"missing"}
+	for i := 0; i < 15; i++ {
+		keys = append(keys, fmt.Sprintf("key_%d", i))
+	}
+
+	values, err := c.GetMany(keys)
+	assert.Len(t, values, 15)
+	assert.Equal(t, "14", values["key_14"])
+
+	var invalid *InvalidParametersError
+	assert.True(t, errors.As(err, &invalid))
+	assert.Equal(t, []string{"missing"}, invalid.Keys)
+	assert.True(t, errors.Is(err, ErrParameterNotFound))
+}
+
+func Test_EnvironmentConfiguration(t *testing.T) {
+	c := EnvironmentConfiguration{UseUpper: true}
+
+	assert.Nil(t, c.Set("GOAWSHELPERS_TEST_KEY", "value"))
+	defer os.Unsetenv("GOAWSHELPERS_TEST_KEY")
+
+	val, err := c.Get("GOAWSHELPERS_TEST_KEY")
+	assert.Nil(t, err)
+	assert.Equal(t, "value", val)
+
+	n := NewEnvironmentConfiguration(true)
+	assert.NotNil(t, n.Values)
+	assert.True(t, n.UseUpper)
+}
+
+func Test_BiConfiguration_SetCreate(t *testing.T) {
+	b, err := NewBiConfiguration(EnvironmentConfiguration{}, nil)
+	assert.Nil(t, err)
+
+	defer os.Unsetenv("GOAWSHELPERS_BI_KEY")
+
+	assert.Nil(t, b.Create("GOAWSHELPERS_BI_KEY", "first"))
+	assert.True(t, errors.Is(b.Create("GOAWSHELPERS_BI_KEY", "second"), ErrParameterAlreadyExists))
+
+	assert.Nil(t, b.Set("GOAWSHELPERS_BI_KEY", "second"))
+	assert.Equal(t, "second", os.Getenv("GOAWSHELPERS_BI_KEY"))
+
+	val, err := b.Get("GOAWSHELPERS_BI_KEY")
+	assert.Nil(t, err)
+	assert.Equal(t, "second", val)
+}
+
+func Test_BiConfiguration_Cache(t *testing.T) {
+	client := newFakeSSM()
+	client.seed("/dev/cached", "remote")
+
+	b := NewBiConfigurationWith(nil, NewSSMConfigurationWithClient(client, "dev", "_"))
+
+	val, err := b.Get("cached")
+	assert.Nil(t, err)
+	assert.Equal(t, "remote", val)
+
+	client.seed("/dev/cached", "rotated")
+	val, _ = b.Get("cached")
+	assert.Equal(t, "remote", val)
+
+	b.ClearCache()
+	val, _ = b.Get("cached")
+	assert.Equal(t, "rotated", val)
+}
+
+func Test_SSMConfiguration_List(t *testing.T) {
+	client := newFakeSSM()
+	c := NewSSMConfigurationWithClient(client, "dev", "_")
+
+	assert.Nil(t, c.SetList("allowed_origins", []string{"https://a.com", "https://b.com"}))
+	assert.Equal(t, ssm.ParameterTypeStringList, *client.lastPut.Type)
+	assert.Equal(t, "https://a.com,https://b.com", *client.lastPut.Value)
+
+	values, err := c.GetList("allowed_origins")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"https://a.com", "https://b.com"}, values)
+
+	assert.NotNil(t, c.SetList("empty", nil))
+	assert.Contains(t, fmt.Sprint(c.SetList("comma", []string{"a,b"})), "contains a comma")
+}
+
+func Test_SSMConfiguration_ListRoundTrip(t *testing.T) {
+	client := newFakeSSM()
+	c := NewSSMConfigurationWithClient(client, "dev", "_")
+	c.secure = true
+
+	assert.Nil(t, c.SetList("names", []string{"John Smith", " Jane Doe "}))
+	values, err := c.GetList("names")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"John Smith", "Jane Doe"}, values)
+	assert.False(t, *client.lastGet.WithDecryption)
+
+	client.seed("/dev/spaced", "a, b ,c")
+	values, _ = c.GetList("spaced")
+	assert.Equal(t, []string{"a", "b", "c"}, values)
+
+	client.seed("/dev/blank", "")
+	values, err = c.GetList("blank")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{}, values)
+}
+
+func Test_SSMConfiguration_GetEnvironmentPaging(t *testing.T) {
+	client := newFakeSSM()
+	for i := 0; i < 25; i++ {
+		client.seed(fmt.Sprintf("/dev/key/%d", i), fmt.Sprint(i))
+	}
+	c := NewSSMConfigurationWithClient(client, "dev", "_")
+
+	values, err := c.GetEnvironment()
+	assert.Nil(t, err)
+	assert.Len(t, values, 25)
+	assert.Equal(t, int64(10), *client.lastPath.MaxResults)
+	assert.False(t, *client.lastPath.WithDecryption)
+}
+
+func Test_SSMConfiguration_Decryption(t *testing.T) {
+	client := newFakeSSM()
+	client.seed("/dev/secret", "value")
+	c := NewSSMConfigurationWithClient(client, "dev", "_")
+
+	_, _ = c.Get("secret")
+	_, _ = c.GetEnvironment()
+	assert.False(t, *client.lastGet.WithDecryption)
+	assert.False(t, *client.lastPath.WithDecryption)
+
+	c.decrypt = true
+	_, _ = c.Get("secret")
+	_, _ = c.GetEnvironment()
+	assert.True(t, *client.lastGet.WithDecryption)
+	assert.True(t, *client.lastPath.WithDecryption)
+
+	c.decrypt = false
+	c.secure = true
+	_, _ = c.Get("secret")
+	assert.True(t, *client.lastGet.WithDecryption)
+}
+
+func Test_SSMConfiguration_Retries(t *testing.T) {
+	config, err := NewSSMConfiguration(SSMConfigurationInit{
+		AwsAccessKey:       "key",
+		AwsSecretAccessKey: "secret",
+		MaxRetries:         7,
+		RetryBaseDelay:     time.Second,
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, 7, config.client.(*ssm.SSM).MaxRetries())
+}
+
+func Test_SSMConfiguration_AssumeRole(t *testing.T) {
+	config, err := NewSSMConfiguration(SSMConfigurationInit{
+		AwsAccessKey:       "key",
+		AwsSecretAccessKey: "secret",
+		AssumeRoleArn:      "arn:aws:iam::123456789012:role/config-reader",
+	})
+
+	assert.Nil(t, err)
+	// static credentials never expire, the assumed role ones are not retrieved yet
+	assert.True(t, config.client.(*ssm.SSM).Config.Credentials.IsExpired())
+}
+
+func Test_SSMConfiguration_DefaultChain(t *testing.T) {
+	config, err := NewSSMConfiguration(SSMConfigurationInit{
+		UseDefaultChain: true,
+	})
+
+	assert.Nil(t, err)
+	assert.NotNil(t, config.client.(*ssm.SSM).Config.Credentials)
+}
+
+func Test_SSMConfigurationFromSession(t *testing.T) {
+	sess, err := session.NewSession(aws.NewConfig().WithRegion("us-east-1"))
+	assert.Nil(t, err)
+
+	c := NewSSMConfigurationFromSession(sess, "dev", "")
+	assert.Equal(t, "us-east-1", *c.client.(*ssm.SSM).Config.Region)
+	assert.Equal(t, "_", c.keyDelimitor)
+}
+
+func Test_SSMConfiguration_Endpoint(t *testing.T) {
+	config, err := NewSSMConfiguration(SSMConfigurationInit{
+		AwsAccessKey:       "key",
+		AwsSecretAccessKey: "secret",
+		Endpoint:           "http://localhost:4566",
+		DisableSSL:         true,
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, "http://localhost:4566", config.client.(*ssm.SSM).Endpoint)
+}
+
+func Test_SSMConfiguration_UseFIPS(t *testing.T) {
+	config, err := NewSSMConfiguration(SSMConfigurationInit{
+		AwsAccessKey:       "key",
+		AwsSecretAccessKey: "secret",
+		Region:             "us-east-1",
+		UseFIPS:            true,
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, "https://ssm-fips.us-east-1.amazonaws.com", config.client.(*ssm.SSM).Endpoint)
+
+	config, err = NewSSMConfiguration(SSMConfigurationInit{
+		AwsAccessKey:       "key",
+		AwsSecretAccessKey: "secret",
+		Region:             "us-east-1",
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, "https://ssm.us-east-1.amazonaws.com", config.client.(*ssm.SSM).Endpoint)
+}
+
+func Test_SSMConfiguration_DeleteIfValue(t *testing.T) {
+	client := newFakeSSM()
+	client.seed("/dev/key", "mine")
+	c := NewSSMConfigurationWithClient(client, "dev", "_")
+
+	err := c.DeleteIfValue("key", "theirs")
+	assert.True(t, errors.Is(err, ErrValueMismatch))
+	assert.Equal(t, "error deleting key key - value mismatch", err.Error())
+	assert.Contains(t, client.params, "/dev/key")
+
+	assert.Nil(t, c.DeleteIfValue("key", "mine"))
+	assert.NotContains(t, client.params, "/dev/key")
+
+	assert.True(t, errors.Is(c.DeleteIfValue("key", "mine"), ErrParameterNotFound))
+}
+
+func Test_SSMConfiguration_DeletePrefix(t *testing.T) {
+	client := newFakeSSM()
+	for i := 0; i < 12; i++ {
+		client.seed(fmt.Sprintf("/pr-1234/service/key%d", i), "value")
+	}
+	client.seed("/pr-1234/other", "value")
+	client.seed("/dev/service/key", "value")
+	c := NewSSMConfigurationWithClient(client, "pr-1234", "_")
+
+	assert.Nil(t, c.DeletePrefix("service"))
+	values, _ := c.GetEnvironment()
+	assert.Equal(t, map[string]string{"other": "value"}, values)
+
+	assert.Nil(t, c.DeletePrefix(""))
+	values, _ = c.GetEnvironment()
+	assert.Empty(t, values)
+	assert.Len(t, client.params, 1)
+}
+
+func Test_configuredRegion(t *testing.T) {
+	os.Unsetenv("AWS_REGION")
+	os.Unsetenv("AWS_DEFAULT_REGION")
+
+	assert.Equal(t, "us-west-2", configuredRegion("us-west-2"))
+	assert.Equal(t, "", configuredRegion(""))
+
+	os.Setenv("AWS_DEFAULT_REGION", "us-east-2")
+	defer os.Unsetenv("AWS_DEFAULT_REGION")
+	assert.Equal(t, "us-east-2", configuredRegion(""))
+
+	os.Setenv("AWS_REGION", "us-east-1")
+	defer os.Unsetenv("AWS_REGION")
+	assert.Equal(t, "us-east-1", configuredRegion(""))
+}
+
+func Test_SSMConfiguration_DefaultRegion(t *testing.T) {
+	os.Unsetenv("AWS_REGION")
+	os.Unsetenv("AWS_DEFAULT_REGION")
+
+	var events []Event
+	logger := LoggerFunc(func(event Event) { events = append(events, event) })
+
+	config, err := NewSSMConfiguration(SSMConfigurationInit{UseDefaultChain: true, Logger: logger})
+	assert.Nil(t, err)
+	assert.Equal(t, "eu-north-1", *config.client.(*ssm.SSM).Config.Region)
+	assert.Equal(t, []Event{{Op: "region", Key: "eu-north-1"}}, events)
+
+	SetDefaultRegion("ap-south-1")
+	defer SetDefaultRegion("eu-north-1")
+	config, err = NewSSMConfiguration(SSMConfigurationInit{UseDefaultChain: true})
+	assert.Nil(t, err)
+	assert.Equal(t, "ap-south-1", *config.client.(*ssm.SSM).Config.Region)
+
+	_, err = NewSSMConfiguration(SSMConfigurationInit{UseDefaultChain: true, StrictRegion: true})
+	assert.Contains(t, fmt.Sprint(err), "no region configured")
+
+	config, err = NewSSMConfiguration(SSMConfigurationInit{UseDefaultChain: true, StrictRegion: true, Region: "us-west-2"})
+	assert.Nil(t, err)
+	assert.Equal(t, "us-west-2", *config.client.(*ssm.SSM).Config.Region)
+}
+
+func Test_SetDefaultRegionConcurrent(t *testing.T) {
+	os.Unsetenv("AWS_REGION")
+	os.Unsetenv("AWS_DEFAULT_REGION")
+	defer SetDefaultRegion("eu-north-1")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			SetDefaultRegion("ap-south-1")
+		}()
+		go func() {
+			defer wg.Done()
+			_, err := NewSSMConfiguration(SSMConfigurationInit{UseDefaultChain: true})
+			assert.Nil(t, err)
+		}()
+	}
+	wg.Wait()
+}
+
+func Test_BiConfiguration_Refresh(t *testing.T) {
+	client := newFakeSSM()
+	client.seed("/dev/password", "old")
+	client.seed("/dev/user", "admin")
+
+	b := NewBiConfigurationWith(nil, NewSSMConfigurationWithClient(client, "dev", "_"))
+
+	val, _ := b.Get("password")
+	assert.Equal(t, "old", val)
+
+	client.seed("/dev/password", "new")
+	assert.Nil(t, b.Refresh())
+	val, _ = b.Get("password")
+	assert.Equal(t, "new", val)
+
+	client.seed("/dev/password", "newer")
+	assert.Nil(t, b.RefreshKey("password"))
+	val, _ = b.Get("password")
+	assert.Equal(t, "newer", val)
+
+	client.err = errors.New("throttled")
+	assert.NotNil(t, b.Refresh())
+	val, _ = b.Get("user")
+	assert.Equal(t, "admin", val)
+}
+
+func Test_BiConfiguration_RefreshKeyCase(t *testing.T) {
+	client := newFakeSSM()
+	client.seed("/dev/goawshelpers/refresh/host", "old")
+	defer os.Unsetenv("GOAWSHELPERS_REFRESH_HOST")
+
+	b := NewBiConfigurationWith(nil, NewSSMConfigurationWithClient(client, "dev", "_"))
+	assert.Nil(t, b.Refresh())
+
+	val, _ := b.Get("GOAWSHELPERS_REFRESH_HOST")
+	assert.Equal(t, "old", val)
+
+	assert.Nil(t, b.Set("GOAWSHELPERS_REFRESH_HOST", "new"))
+	val, _ = b.Get("goawshelpers_refresh_host")
+	assert.Equal(t, "new", val)
+
+	// the env value read back by Refresh loses to ssm like in Get
+	client.seed("/dev/goawshelpers/refresh/host", "remote")
+	assert.Nil(t, b.Refresh())
+	val, _ = b.Get("GOAWSHELPERS_REFRESH_HOST")
+	assert.Equal(t, "remote", val)
+
+	b.PreferEnv = true
+	assert.Nil(t, b.Refresh())
+	val, _ = b.Get("GOAWSHELPERS_REFRESH_HOST")
+	assert.Equal(t, "new", val)
+	val, _ = b.Get("goawshelpers_refresh_host")
+	assert.Equal(t, "remote", val)
+}
+
+func Test_BiConfiguration_CaseSensitiveRemote(t *testing.T) {
+	defer os.Unsetenv("goawshelpers_case")
+
+	b := NewBiConfigurationWith(nil, NewMemoryConfiguration(map[string]string{"GOAWSHELPERS_CASE": "upper", "goawshelpers_case": "lower"}))
+	assert.Nil(t, b.Refresh())
+
+	val, err := b.Get("goawshelpers_case")
+	assert.Nil(t, err)
+	assert.Equal(t, "lower", val)
+
+	val, err = b.Get("GOAWSHELPERS_CASE")
+	assert.Nil(t, err)
+	assert.Equal(t, "upper", val)
+
+	assert.Nil(t, b.Set("goawshelpers_case", "changed"))
+	val, _ = b.Get("GOAWSHELPERS_CASE")
+	assert.Equal(t, "upper", val)
+}
+
+func Test_ConcurrentAccess(t *testing.T) {
+	client := newFakeSSM()
+	client.seed("/dev/shared", "value")
+
+	b := NewBiConfigurationWith(nil, NewSSMConfigurationWithClient(client, "dev", "_"))
+
+	env := NewEnvironmentConfiguration(false)
+	defer os.Unsetenv("GOAWSHELPERS_CONCURRENT")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			_, _ = b.Get("shared")
+			_ = b.Set(fmt.Sprintf("key_%d", i), "value")
+			_ = b.Refresh()
+			b.ClearCache()
+
+			_ = env.Set("GOAWSHELPERS_CONCURRENT", fmt.Sprint(i))
+			_, _ = env.Get("GOAWSHELPERS_CONCURRENT")
+			_, _ = env.GetEnvironment()
+		}(i)
+	}
+	wg.Wait()
+}
+
+func Test_SSMConfiguration_Tags(t *testing.T) {
+	client := newFakeSSM()
+	c := NewSSMConfigurationWithClient(client, "dev", "_")
+	c.tags = map[string]string{"team": "platform", "app": "api"}
+
+	assert.Nil(t, c.Create("created", "value"))
+	assert.Len(t, client.lastPut.Tags, 2)
+	assert.Equal(t, map[string]string{"team": "platform", "app": "api"}, client.tags["/dev/created"])
+
+	err := c.SetWithOptions(context.Background(), "updated", "value", PutOptions{
+		Tags: map[string]string{"app": "worker", "owner": "alice"},
+	})
+	assert.Nil(t, err)
+	assert.Nil(t, client.lastPut.Tags)
+	assert.Equal(t, map[string]string{"team": "platform", "app": "worker", "owner": "alice"}, client.tags["/dev/updated"])
+}
+
+func Test_SSMConfiguration_Tier(t *testing.T) {
+	client := newFakeSSM()
+	c := NewSSMConfigurationWithClient(client, "dev", "_")
+
+	assert.Nil(t, c.Set("key", "value"))
+	assert.Equal(t, ssm.ParameterTierIntelligentTiering, *client.lastPut.Tier)
+
+	assert.Nil(t, c.SetWithOptions(context.Background(), "key", "value", PutOptions{Tier: ssm.ParameterTierAdvanced}))
+	assert.Equal(t, ssm.ParameterTierAdvanced, *client.lastPut.Tier)
+
+	err := c.SetWithOptions(context.Background(), "key", "value", PutOptions{Tier: "Advance"})
+	assert.Contains(t, fmt.Sprint(err), `invalid parameter tier "Advance"`)
+
+	_, err = NewSSMConfiguration(SSMConfigurationInit{UseDefaultChain: true, Tier: "premium"})
+	assert.NotNil(t, err)
+}
+
+func Test_SSMConfiguration_Type(t *testing.T) {
+	client := newFakeSSM()
+	c := NewSSMConfigurationWithClient(client, "dev", "_")
+
+	assert.Nil(t, c.SetWithOptions(context.Background(), "key", "a,b", PutOptions{Type: ssm.ParameterTypeStringList}))
+	assert.Equal(t, ssm.ParameterTypeStringList, *client.lastPut.Type)
+
+	err := c.SetWithOptions(context.Background(), "key", "value", PutOptions{Type: "Json"})
+	assert.Contains(t, fmt.Sprint(err), `invalid parameter type "Json"`)
+
+	assert.Nil(t, c.SetWithOptions(context.Background(), "key", "{}", PutOptions{Type: "Json", AllowUnknownType: true}))
+	assert.Equal(t, "Json", *client.lastPut.Type)
+}
+
+func Test_SSMConfiguration_SetTyped(t *testing.T) {
+	client := newFakeSSM()
+	c := NewSSMConfigurationWithClient(client, "dev", "_")
+	c.kmsKeyID = "alias/app"
+
+	assert.Nil(t, c.CreateTyped("api_key", "secret", ssm.ParameterTypeSecureString))
+	assert.Equal(t, ssm.ParameterTypeSecureString, *client.lastPut.Type)
+	assert.Equal(t, "alias/app", *client.lastPut.KeyId)
+
+	assert.Nil(t, c.Set("db_host", "localhost"))
+	assert.Equal(t, ssm.ParameterTypeString, *client.lastPut.Type)
+
+	assert.Nil(t, c.SetTyped("api_key", "rotated", ssm.ParameterTypeSecureString))
+	assert.Equal(t, ssm.ParameterTypeSecureString, *client.params["/dev/api/key"].Type)
+	assert.Equal(t, "rotated", *client.params["/dev/api/key"].Value)
+
+	assert.NotNil(t, c.SetTyped("api_key", "value", "Secure"))
+}
+
+func Test_SSMConfiguration_SetWithExpiration(t *testing.T) {
+	client := newFakeSSM()
+	c := NewSSMConfigurationWithClient(client, "dev", "_")
+	expireAt := time.Date(2100, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	assert.Nil(t, c.SetWithExpiration("token", "value", expireAt))
+	assert.Equal(t, ssm.ParameterTierAdvanced, *client.lastPut.Tier)
+	assert.JSONEq(t, `[{"Type":"Expiration","Version":"1.0","Attributes":{"Timestamp":"2100-01-02T03:04:05Z"}}]`, *client.lastPut.Policies)
+
+	err := c.SetWithExpiration("token", "value", time.Now().Add(-time.Minute))
+	assert.Contains(t, fmt.Sprint(err), "not in the future")
+
+	c.tier = ssm.ParameterTierStandard
+	err = c.SetWithExpiration("token", "value", expireAt)
+	assert.Contains(t, fmt.Sprint(err), "parameter policies require the Advanced tier, got Standard")
+}
+
+func Test_SSMConfiguration_GetVersionAndLabel(t *testing.T) {
+	client := newFakeSSM()
+	client.seed("/dev/feature/flag", "on")
+	c := NewSSMConfigurationWithClient(client, "dev", "_")
+
+	val, err := c.GetVersion("feature_flag", 3)
+	assert.Nil(t, err)
+	assert.Equal(t, "on", val)
+	assert.Equal(t, "/dev/feature/flag:3", *client.lastGet.Name)
+
+	_, err = c.GetByLabel("feature_flag", "Stable")
+	assert.Nil(t, err)
+	assert.Equal(t, "/dev/feature/flag:Stable", *client.lastGet.Name)
+}
+
+func Test_SSMConfiguration_GetHistory(t *testing.T) {
+	client := newFakeSSM()
+	c := NewSSMConfigurationWithClient(client, "dev", "_")
+
+	assert.Nil(t, c.Set("password", "first"))
+	assert.Nil(t, c.Set("password", "second"))
+	assert.Nil(t, c.Set("password", "third"))
+
+	history, err := c.GetHistory("password")
+	assert.Nil(t, err)
+	assert.Len(t, history, 3)
+	assert.Equal(t, int64(3), history[0].Version)
+	assert.Equal(t, "third", history[0].Value)
+	assert.Equal(t, "first", history[2].Value)
+	assert.Equal(t, "arn:aws:iam::123456789012:user/test", history[0].LastModifiedUser)
+
+	_, err = c.GetHistory("missing")
+	assert.True(t, errors.Is(err, ErrParameterNotFound))
+}
+
+func Test_NewEnvironmentConfigurationFromOS(t *testing.T) {
+	os.Setenv("GOAWSHELPERS_OS_DB_HOST", "localhost")
+	os.Setenv("GOAWSHELPERS_OS_DB_PORT", "5432")
+	defer os.Unsetenv("GOAWSHELPERS_OS_DB_HOST")
+	defer os.Unsetenv("GOAWSHELPERS_OS_DB_PORT")
+
+	c := NewEnvironmentConfigurationFromOS("GOAWSHELPERS_OS_")
+
+	values, err := c.GetEnvironment()
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"DB_HOST": "localhost", "DB_PORT": "5432"}, values)
+
+	val, err := c.Get("DB_HOST")
+	assert.Nil(t, err)
+	assert.Equal(t, "localhost", val)
+
+	assert.Nil(t, c.Set("DB_USER", "admin"))
+	defer os.Unsetenv("GOAWSHELPERS_OS_DB_USER")
+	assert.Equal(t, "admin", os.Getenv("GOAWSHELPERS_OS_DB_USER"))
+
+	assert.Nil(t, c.Delete("DB_PORT"))
+	assert.Equal(t, "", os.Getenv("GOAWSHELPERS_OS_DB_PORT"))
+
+	all := NewEnvironmentConfigurationFromOS("")
+	assert.Equal(t, "localhost", all.Values["GOAWSHELPERS_OS_DB_HOST"])
+}
+
+func Test_BiConfiguration_PreferEnv(t *testing.T) {
+	client := newFakeSSM()
+	client.seed("/dev/goawshelpers/prefer", "remote")
+	client.seed("/dev/remote/only", "remote")
+
+	os.Setenv("goawshelpers_prefer", "local")
+	defer os.Unsetenv("goawshelpers_prefer")
+
+	b := NewBiConfigurationWith(nil, NewSSMConfigurationWithClient(client, "dev", "_"))
+
+	val, _ := b.Get("goawshelpers_prefer")
+	assert.Equal(t, "remote", val)
+
+	b.ClearCache()
+	b.PreferEnv = true
+
+	val, _ = b.Get("goawshelpers_prefer")
+	assert.Equal(t, "local", val)
+
+	val, _ = b.Get("remote_only")
+	assert.Equal(t, "remote", val)
+
+	values, err := b.GetEnvironment()
+	assert.Nil(t, err)
+	assert.Equal(t, "local", values["goawshelpers_prefer"])
+
+	b.PreferEnv = false
+	values, _ = b.GetEnvironment()
+	assert.Equal(t, "remote", values["goawshelpers_prefer"])
+}
+
+func Test_NewBiConfigurationWith(t *testing.T) {
+	remote := NewMemoryConfiguration(map[string]string{"token": "remote"})
+	env := NewEnvironmentConfiguration(false)
+
+	b := NewBiConfigurationWith(env, remote)
+
+	val, err := b.Get("token")
+	assert.Nil(t, err)
+	assert.Equal(t, "remote", val)
+
+	assert.Nil(t, b.Set("fresh", "value"))
+	val, err = remote.Get("fresh")
+	assert.Nil(t, err)
+	assert.Equal(t, "value", val)
+
+	b = NewBiConfigurationWith(nil, nil)
+	_, err = b.Get("token")
+	assert.True(t, errors.Is(err, ErrParameterNotFound))
+}
+
+func Test_SSMConfiguration_KeyCase(t *testing.T) {
+	config, err := NewSSMConfiguration(SSMConfigurationInit{
+		AwsAccessKey:       "key",
+		AwsSecretAccessKey: "secret",
+		Env:                "dev",
+		PreserveKeyCase:    true,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "/dev/MyKey/Sub", config.path("MyKey_Sub"))
+
+	client := newFakeSSM()
+	client.seed("/dev/MyKey", "legacy")
+	c := NewSSMConfigurationWithClient(client, "dev", "_")
+	c.normalize = config.normalize
+
+	val, err := c.Get("MyKey")
+	assert.Nil(t, err)
+	assert.Equal(t, "legacy", val)
+
+	values, err := c.GetEnvironment()
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"MyKey": "legacy"}, values)
+
+	c.normalize = nil
+	_, err = c.Get("MyKey")
+	assert.True(t, errors.Is(err, ErrParameterNotFound))
+
+	config, err = NewSSMConfiguration(SSMConfigurationInit{
+		AwsAccessKey:       "key",
+		AwsSecretAccessKey: "secret",
+		Env:                "dev",
+		PreserveKeyCase:    true,
+		KeyNormalizer:      strings.ToUpper,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "/dev/MYKEY", config.path("mykey"))
+}
+
+func Test_SSMConfiguration_GetPath(t *testing.T) {
+	client := newFakeSSM()
+	c := NewSSMConfigurationWithClient(client, "dev", ".")
+
+	assert.Nil(t, c.SetPath("db.internal", "service", "DB_HOST"))
+	assert.Equal(t, "/dev/service/db_host", *client.lastPut.Name)
+
+	val, err := c.GetPath("service", "db_host")
+	assert.Nil(t, err)
+	assert.Equal(t, "db.internal", val)
+
+	val, err = c.Get("service.db_host")
+	assert.Nil(t, err)
+	assert.Equal(t, "db.internal", val)
+
+	_, err = c.GetPath("service", "missing")
+	assert.True(t, errors.Is(err, ErrParameterNotFound))
+
+	_, err = c.GetPath()
+	assert.NotNil(t, err)
+	_, err = c.GetPath("service", "")
+	assert.NotNil(t, err)
+}
+
+func Test_SSMConfiguration_CreateExisting(t *testing.T) {
+	client := newFakeSSM()
+	client.seed("/dev/existing", "value")
+	c := NewSSMConfigurationWithClient(client, "dev", "_")
+
+	err := c.Create("existing", "other")
+	assert.True(t, errors.Is(err, ErrParameterAlreadyExists))
+	assert.False(t, errors.Is(err, ErrParameterNotFound))
+}
+
+func Test_SSMConfiguration_Keys(t *testing.T) {
+	client := newFakeSSM()
+	client.seed("/dev/db/password", "secret")
+	client.seed("/dev/api", "key")
+	client.seed("/prod/api", "other")
+	c := NewSSMConfigurationWithClient(client, "dev", "_")
+
+	keys, err := c.Keys()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"api", "db_password"}, keys)
+
+	client.err = errors.New("denied")
+	_, err = c.Keys()
+	assert.NotNil(t, err)
+}
+
+func Test_SSMConfiguration_GetAll(t *testing.T) {
+	client := newFakeSSM()
+	for i := 0; i < 12; i++ {
+		client.seed(fmt.Sprintf("/dev/key%02d", i), fmt.Sprint(i))
+	}
+	client.seed("/dev/a/b", "1")
+	client.seed("/dev/a_b", "2")
+	client.seed("/prod/api", "other")
+	client.denied = map[string]bool{"/dev/key03": true}
+	c := NewSSMConfigurationWithClient(client, "dev", "_")
+
+	values, err := c.GetAll()
+	assert.Len(t, values, 11)
+	assert.Equal(t, "0", values["key00"])
+	assert.Equal(t, "11", values["key11"])
+
+	var errs KeyErrors
+	assert.True(t, errors.As(err, &errs))
+	assert.Len(t, errs, 2)
+	assert.NotNil(t, errs["key03"])
+	assert.True(t, errors.Is(errs["a_b"], ErrKeyCollision))
+
+	client.denied = nil
+	delete(client.params, "/dev/a/b")
+	values, err = c.GetAll()
+	assert.Nil(t, err)
+	assert.Len(t, values, 13)
+
+	client.err = errors.New("denied")
+	_, err = c.GetAll()
+	assert.NotNil(t, err)
+}
+
+func Test_SSMConfiguration_FlatNames(t *testing.T) {
+	client := newFakeSSM()
+	client.seed("/dev/myapp.db.url", "postgres://localhost")
+	client.seed("/dev/myapp.db_user", "admin")
+	client.seed("/dev/other.key", "value")
+	client.seed("/dev/nested/key", "hierarchical")
+	c := NewSSMConfigurationWithClient(client, "dev", "_")
+	c.flat = true
+
+	val, err := c.Get("myapp.db.url")
+	assert.Nil(t, err)
+	assert.Equal(t, "postgres://localhost", val)
+
+	assert.Nil(t, c.Set("myapp.db.host", "localhost"))
+	assert.Equal(t, "localhost", *client.params["/dev/myapp.db.host"].Value)
+
+	values, err := c.GetEnvironment()
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{
+		"myapp.db.url":  "postgres://localhost",
+		"myapp.db_user": "admin",
+		"myapp.db.host": "localhost",
+		"other.key":     "value",
+	}, values)
+	assert.False(t, *client.lastPath.Recursive)
+
+	values, err = c.GetEnvironmentByPrefix("myapp.db.")
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"myapp.db.url": "postgres://localhost", "myapp.db.host": "localhost"}, values)
+
+	keys, err := c.Keys()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"myapp.db.host", "myapp.db.url", "myapp.db_user", "other.key"}, keys)
+
+	assert.Nil(t, c.DeletePrefix("myapp."))
+	assert.Len(t, client.params, 2)
+}
+
+func Test_SSMConfiguration_GetEnvironmentDetailed(t *testing.T) {
+	client := newFakeSSM()
+	client.seed("/dev/db/host", "localhost")
+	client.seed("/prod/db/host", "db.internal")
+	c := NewSSMConfigurationWithClient(client, "dev", "_")
+	assert.Nil(t, c.SetList("hosts", []string{"a", "b"}))
+
+	params, err := c.GetEnvironmentDetailed()
+	assert.Nil(t, err)
+	assert.Len(t, params, 2)
+	assert.Equal(t, "/dev/db/host", params["db_host"].Name)
+	assert.Equal(t, "localhost", params["db_host"].Value)
+	assert.Equal(t, ssm.ParameterTypeString, params["db_host"].Type)
+	assert.Equal(t, int64(1), params["db_host"].Version)
+	assert.False(t, params["db_host"].LastModifiedDate.IsZero())
+	assert.Equal(t, ssm.ParameterTypeStringList, params["hosts"].Type)
+	assert.Equal(t, "a,b", params["hosts"].Value)
+
+	client.seed("/dev/db_host", "other")
+	_, err = c.GetEnvironmentDetailed()
+	assert.True(t, errors.Is(err, ErrKeyCollision))
+}
+
+func Test_SSMConfiguration_GetEnvironmentByPrefix(t *testing.T) {
+	client := newFakeSSM()
+	client.seed("/dev/database/host", "localhost")
+	client.seed("/dev/database/replica/host", "replica")
+	client.seed("/dev/databases", "other")
+	client.seed("/dev/api", "key")
+	c := NewSSMConfigurationWithClient(client, "dev", "_")
+
+	values, err := c.GetEnvironmentByPrefix("database")
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"database_host": "localhost", "database_replica_host": "replica"}, values)
+	assert.Equal(t, "/dev/database/", *client.lastPath.Path)
+
+	values, err = c.GetEnvironmentByPrefix("database_")
+	assert.Nil(t, err)
+	assert.Len(t, values, 2)
+
+	values, err = c.GetEnvironmentByPrefix("missing")
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{}, values)
+}
+
+func Test_SSMConfiguration_GetForEnv(t *testing.T) {
+	client := newFakeSSM()
+	client.seed("/dev/db/host", "dev-db")
+	client.seed("/prod/db/host", "prod-db")
+	c := NewSSMConfigurationWithClient(client, "dev", "_")
+
+	val, err := c.GetForEnv("PROD", "db_host")
+	assert.Nil(t, err)
+	assert.Equal(t, "prod-db", val)
+
+	val, _ = c.Get("db_host")
+	assert.Equal(t, "dev-db", val)
+
+	_, err = c.GetForEnv("staging", "db_host")
+	assert.True(t, errors.Is(err, ErrParameterNotFound))
+}
+
+func Test_SSMConfiguration_Description(t *testing.T) {
+	client := newFakeSSM()
+	c := NewSSMConfigurationWithClient(client, "dev", "_")
+
+	assert.Nil(t, c.CreateWithOptions(context.Background(), "port", "8080", PutOptions{Description: "HTTP listen port"}))
+	assert.Equal(t, "HTTP listen port", *client.lastPut.Description)
+
+	assert.Nil(t, c.Set("port", "9090"))
+	assert.Nil(t, client.lastPut.Description)
+}
+
+func Test_SSMConfiguration_AllowedPattern(t *testing.T) {
+	client := newFakeSSM()
+	c := NewSSMConfigurationWithClient(client, "dev", "_")
+
+	assert.Nil(t, c.CreateWithOptions(context.Background(), "port", "8080", PutOptions{AllowedPattern: `^\d{1,5}$`}))
+	assert.Equal(t, `^\d{1,5}$`, *client.lastPut.AllowedPattern)
+
+	err := c.Set("port", "eighty")
+	assert.True(t, errors.Is(err, ErrPatternMismatch))
+	assert.Equal(t, `error setting an entry with key port - value does not match the allowed pattern - ParameterPatternMismatchException: Parameter value, eighty, failed to satisfy constraint: Member must satisfy regular expression pattern: ^\d{1,5}$`, err.Error())
+
+	assert.Nil(t, c.Set("port", "80"))
+}
+
+func Test_BiConfiguration_Errors(t *testing.T) {
+	client := newFakeSSM()
+	b := NewBiConfigurationWith(nil, NewSSMConfigurationWithClient(client, "dev", "_"))
+
+	_, err := b.Get("missing")
+	assert.Equal(t, "no value with key missing - parameter not found", err.Error())
+
+	b.PreferEnv = true
+	_, err = b.Get("missing")
+	assert.Equal(t, "no value with key missing - parameter not found", err.Error())
+
+	assert.Nil(t, os.Setenv("GOAWSHELPERS_DENIED", "local"))
+	defer os.Unsetenv("GOAWSHELPERS_DENIED")
+
+	client.err = awserr.New("AccessDeniedException", "not authorized to perform ssm:GetParameter", nil)
+	b.PreferEnv = false
+	_, err = b.Get("GOAWSHELPERS_DENIED")
+	assert.NotNil(t, err)
+	assert.False(t, errors.Is(err, ErrParameterNotFound))
+}
+
+func Test_BiConfiguration_Delete(t *testing.T) {
+	client := newFakeSSM()
+	b := NewBiConfigurationWith(nil, NewSSMConfigurationWithClient(client, "dev", "_"))
+	defer os.Unsetenv("GOAWSHELPERS_BI_DELETE")
+
+	assert.Nil(t, b.Set("GOAWSHELPERS_BI_DELETE", "value"))
+	assert.Nil(t, b.Delete("GOAWSHELPERS_BI_DELETE"))
+	assert.NotContains(t, client.params, "/dev/goawshelpers/bi/delete")
+	assert.Equal(t, "", os.Getenv("GOAWSHELPERS_BI_DELETE"))
+
+	// a variable provided by the OS alone is not expected in ssm
+	assert.Nil(t, os.Setenv("GOAWSHELPERS_BI_DELETE", "os"))
+	assert.Nil(t, b.Delete("GOAWSHELPERS_BI_DELETE"))
+	assert.Equal(t, "", os.Getenv("GOAWSHELPERS_BI_DELETE"))
+
+	b.StrictDelete = true
+	assert.Nil(t, os.Setenv("GOAWSHELPERS_BI_DELETE", "os"))
+	assert.Nil(t, b.Delete("GOAWSHELPERS_BI_DELETE"))
+	assert.Equal(t, "", os.Getenv("GOAWSHELPERS_BI_DELETE"))
+	b.StrictDelete = false
+
+	// missing from both
+	err := b.Delete("GOAWSHELPERS_BI_DELETE")
+	assert.True(t, errors.Is(err, ErrParameterNotFound))
+
+	var keyErrs KeyErrors
+	assert.True(t, errors.As(err, &keyErrs))
+	assert.Contains(t, keyErrs, "remote")
+	assert.NotContains(t, keyErrs, "env")
+
+	assert.Nil(t, b.Set("GOAWSHELPERS_BI_DELETE", "value"))
+	client.err = awserr.New("AccessDeniedException", "not authorized to perform ssm:DeleteParameter", nil)
+
+	b.StrictDelete = true
+	assert.NotNil(t, b.Delete("GOAWSHELPERS_BI_DELETE"))
+	assert.Equal(t, "value", os.Getenv("GOAWSHELPERS_BI_DELETE"))
+
+	b.StrictDelete = false
+	err = b.Delete("GOAWSHELPERS_BI_DELETE")
+	assert.Contains(t, err.Error(), "not authorized to perform ssm:DeleteParameter")
+	assert.Equal(t, "", os.Getenv("GOAWSHELPERS_BI_DELETE"))
+
+	client.err = nil
+	assert.Contains(t, client.params, "/dev/goawshelpers/bi/delete")
+}
+
+func Test_SSMConfiguration_GetEnvironmentCollision(t *testing.T) {
+	client := newFakeSSM()
+	client.seed("/dev/a/b/c", "nested")
+	client.seed("/dev/a_b/c", "flat")
+	client.seed("/dev/other", "value")
+	c := NewSSMConfigurationWithClient(client, "dev", "_")
+
+	_, err := c.GetEnvironment()
+	assert.True(t, errors.Is(err, ErrKeyCollision))
+
+	var keyErrs KeyErrors
+	assert.True(t, errors.As(err, &keyErrs))
+	assert.Equal(t, "/dev/a/b/c and /dev/a_b/c - key collision", keyErrs["a_b_c"].Error())
+
+	values, err := c.GetEnvironmentPaths()
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"/dev/a/b/c": "nested", "/dev/a_b/c": "flat", "/dev/other": "value"}, values)
+}
+
+func Test_EnvironmentConfiguration_TreatEmptyAsSet(t *testing.T) {
+	assert.Nil(t, os.Setenv("GOAWSHELPERS_EMPTY", ""))
+	defer os.Unsetenv("GOAWSHELPERS_EMPTY")
+
+	c := NewEnvironmentConfiguration(false)
+	_, err := c.Get("GOAWSHELPERS_EMPTY")
+	assert.True(t, errors.Is(err, ErrParameterNotFound))
+
+	c.TreatEmptyAsSet = true
+	val, err := c.Get("GOAWSHELPERS_EMPTY")
+	assert.Nil(t, err)
+	assert.Equal(t, "", val)
+
+	_, err = c.Get("GOAWSHELPERS_UNSET")
+	assert.True(t, errors.Is(err, ErrParameterNotFound))
+}
+
+func Test_EnvironmentConfiguration_Create(t *testing.T) {
+	defer os.Unsetenv("GOAWSHELPERS_CREATED")
+
+	c := NewEnvironmentConfiguration(false)
+	assert.Nil(t, c.Create("GOAWSHELPERS_CREATED", "first"))
+	assert.Equal(t, "first", os.Getenv("GOAWSHELPERS_CREATED"))
+
+	err := c.Create("GOAWSHELPERS_CREATED", "second")
+	assert.True(t, errors.Is(err, ErrParameterAlreadyExists))
+	assert.Equal(t, "error creating a new entry with key GOAWSHELPERS_CREATED - parameter already exists", err.Error())
+	assert.Equal(t, "first", os.Getenv("GOAWSHELPERS_CREATED"))
+
+	assert.Nil(t, os.Setenv("GOAWSHELPERS_CREATED", ""))
+	assert.True(t, errors.Is(c.Create("GOAWSHELPERS_CREATED", "third"), ErrParameterAlreadyExists))
+}
+
+func Test_EnvironmentConfiguration_Defaults(t *testing.T) {
+	defer os.Unsetenv("GOAWSHELPERS_PORT")
+
+	c := NewEnvironmentConfiguration(false)
+	c.Defaults = map[string]string{"GOAWSHELPERS_PORT": "8080", "GOAWSHELPERS_SUFFIX": ""}
+
+	val, err := c.Get("GOAWSHELPERS_PORT")
+	assert.Nil(t, err)
+	assert.Equal(t, "8080", val)
+
+	val, err = c.Get("GOAWSHELPERS_SUFFIX")
+	assert.Nil(t, err)
+	assert.Equal(t, "", val)
+
+	assert.Nil(t, os.Setenv("GOAWSHELPERS_PORT", "9090"))
+	val, _ = c.Get("GOAWSHELPERS_PORT")
+	assert.Equal(t, "9090", val)
+
+	// defaults are not recorded
+	values, _ := c.GetEnvironment()
+	assert.Equal(t, map[string]string{"GOAWSHELPERS_PORT": "9090"}, values)
+
+	_, err = c.Get("GOAWSHELPERS_UNSET")
+	assert.True(t, errors.Is(err, ErrParameterNotFound))
+}
+
+func Test_BiConfiguration_Defaults(t *testing.T) {
+	client := newFakeSSM()
+	client.seed("/dev/goawshelpers/timeout", "30s")
+
+	env := NewEnvironmentConfiguration(false)
+	env.Defaults = map[string]string{"GOAWSHELPERS_TIMEOUT": "10s", "GOAWSHELPERS_RETRIES": "3"}
+
+	var sources []string
+	b := NewBiConfigurationWith(env, NewSSMConfigurationWithClient(client, "dev", "_"))
+	b.PreferEnv = true
+	b.Logger = LoggerFunc(func(event Event) { sources = append(sources, event.Source) })
+
+	// the ssm value beats the default even with PreferEnv
+	val, err := b.Get("GOAWSHELPERS_TIMEOUT")
+	assert.Nil(t, err)
+	assert.Equal(t, "30s", val)
+
+	val, err = b.Get("GOAWSHELPERS_RETRIES")
+	assert.Nil(t, err)
+	assert.Equal(t, "3", val)
+	assert.Equal(t, []string{"env", "remote", "env", "remote", "default"}, sources)
+
+	values, err := b.GetEnvironment()
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"goawshelpers_timeout": "30s"}, values)
+
+	// defaults are not cached, so a value created later is picked up
+	client.seed("/dev/goawshelpers/retries", "5")
+	val, _ = b.Get("GOAWSHELPERS_RETRIES")
+	assert.Equal(t, "5", val)
+}
+
+func Test_SSMConfiguration_SetIfChanged(t *testing.T) {
+	client := newFakeSSM()
+	client.seed("/dev/key", "same")
+	c := NewSSMConfigurationWithClient(client, "dev", "_")
+
+	changed, err := c.SetIfChanged("key", "same")
+	assert.Nil(t, err)
+	assert.False(t, changed)
+	assert.Nil(t, client.lastPut)
+
+	changed, err = c.SetIfChanged("key", "different")
+	assert.Nil(t, err)
+	assert.True(t, changed)
+	assert.Equal(t, int64(2), *client.params["/dev/key"].Version)
+
+	changed, err = c.SetIfChanged("fresh", "value")
+	assert.Nil(t, err)
+	assert.True(t, changed)
+
+	client.err = errors.New("denied")
+	changed, err = c.SetIfChanged("key", "other")
+	assert.NotNil(t, err)
+	assert.False(t, changed)
+}
+
+func Test_SSMConfiguration_DescribeKey(t *testing.T) {
+	client := newFakeSSM()
+	c := NewSSMConfigurationWithClient(client, "dev", "_")
+	c.secure = true
+	c.kmsKeyID = "alias/app"
+
+	assert.Nil(t, c.CreateWithOptions(context.Background(), "db_port", "5432", PutOptions{
+		Description:    "database port",
+		AllowedPattern: `^\d+$`,
+		Tier:           ssm.ParameterTierAdvanced,
+		Policies:       `[{"Type":"Expiration"}]`,
+	}))
+
+	param, err := c.DescribeKey("db_port")
+	assert.Nil(t, err)
+	assert.Equal(t, "/dev/db/port", param.Name)
+	assert.Equal(t, "5432", param.Value)
+	assert.Equal(t, ssm.ParameterTypeSecureString, param.Type)
+	assert.Equal(t, "database port", param.Description)
+	assert.Equal(t, `^\d+$`, param.AllowedPattern)
+	assert.Equal(t, ssm.ParameterTierAdvanced, param.Tier)
+	assert.Equal(t, "alias/app", param.KmsKeyId)
+	assert.Equal(t, []string{`[{"Type":"Expiration"}]`}, param.Policies)
+
+	param, err = c.DescribeKey("db_port:1")
+	assert.Nil(t, err)
+	assert.Equal(t, "5432", param.Value)
+	assert.Equal(t, "/dev/db/port:1", *client.lastGet.Name)
+	assert.Equal(t, "database port", param.Description)
+
+	_, err = c.DescribeKey("missing")
+	assert.True(t, errors.Is(err, ErrParameterNotFound))
+}
+
+func Test_SSMConfiguration_GetWithMetadata(t *testing.T) {
+	client := newFakeSSM()
+	c := NewSSMConfigurationWithClient(client, "dev", "_")
+	assert.Nil(t, c.Set("db_host", "v1"))
+	assert.Nil(t, c.Set("db_host", "v2"))
+
+	param, err := c.GetWithMetadata("db_host")
+	assert.Nil(t, err)
+	assert.Equal(t, "/dev/db/host", param.Name)
+	assert.Equal(t, "v2", param.Value)
+	assert.Equal(t, ssm.ParameterTypeString, param.Type)
+	assert.Equal(t, int64(2), param.Version)
+	assert.False(t, param.LastModifiedDate.IsZero())
+
+	_, err = c.GetWithMetadata("missing")
+	assert.True(t, errors.Is(err, ErrParameterNotFound))
+}
+
+func Test_SSMConfiguration_SetIfVersion(t *testing.T) {
+	client := newFakeSSM()
+	c := NewSSMConfigurationWithClient(client, "dev", "_")
+
+	assert.Nil(t, c.SetIfVersion("key", "v1", 0))
+	assert.True(t, errors.Is(c.SetIfVersion("key", "again", 0), ErrVersionConflict))
+
+	assert.Nil(t, c.SetIfVersion("key", "v2", 1))
+	err := c.SetIfVersion("key", "stale", 1)
+	assert.True(t, errors.Is(err, ErrVersionConflict))
+	assert.Equal(t, "error setting an entry with key key - expected version 1, found 2 - version conflict", err.Error())
+
+	val, _ := c.Get("key")
+	assert.Equal(t, "v2", val)
+
+	assert.True(t, errors.Is(c.SetIfVersion("missing", "value", 3), ErrVersionConflict))
+}
+
+// nilValueSSM returns parameters without a value, which the SDK types allow
+type nilValueSSM struct {
+	*fakeSSM
+	empty bool
+}
+
+func (f nilValueSSM) GetParameterWithContext(ctx aws.Context, input *ssm.GetParameterInput, opts ...request.Option) (*ssm.GetParameterOutput, error) {
+	if f.empty {
+		return &ssm.GetParameterOutput{}, nil
+	}
+	return &ssm.GetParameterOutput{Parameter: &ssm.Parameter{Name: input.Name}}, nil
+}
+
+func Test_SSMConfiguration_NilValue(t *testing.T) {
+	for _, empty := range []bool{true, false} {
+		c := NewSSMConfigurationWithClient(nilValueSSM{fakeSSM: newFakeSSM(), empty: empty}, "dev", "_")
+
+		_, err := c.Get("key")
+		assert.Equal(t, "error retrieving key key - no value returned for parameter /dev/key", fmt.Sprint(err))
+
+		_, err = c.GetAndDecrypt("key")
+		assert.NotNil(t, err)
+		_, err = c.GetList("key")
+		assert.NotNil(t, err)
+		_, err = c.GetWithMetadata("key")
+		assert.NotNil(t, err)
+	}
+}
+
+func Test_SSMConfiguration_Namespace(t *testing.T) {
+	config, err := NewSSMConfiguration(SSMConfigurationInit{
+		AwsAccessKey:       "key",
+		AwsSecretAccessKey: "secret",
+		Env:                "dev",
+		Namespace:          "/billing/",
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "/dev/billing/db/host", config.path("db_host"))
+
+	client := newFakeSSM()
+	client.seed("/dev/billing/db/host", "billing-db")
+	client.seed("/dev/shipping/db/host", "shipping-db")
+	client.seed("/prod/billing/db/host", "prod-db")
+	c := NewSSMConfigurationWithClient(client, "dev", "_")
+	c.namespace = config.namespace
+
+	val, err := c.Get("db_host")
+	assert.Nil(t, err)
+	assert.Equal(t, "billing-db", val)
+
+	values, err := c.GetEnvironment()
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"db_host": "billing-db"}, values)
+
+	val, _ = c.GetForEnv("prod", "db_host")
+	assert.Equal(t, "prod-db", val)
+
+	c.namespace = ""
+	values, _ = c.GetEnvironment()
+	assert.Len(t, values, 2)
+}
+
+func Test_SSMConfiguration_ListEnvironments(t *testing.T) {
+	client := newFakeSSM()
+	c := NewSSMConfigurationWithClient(client, "dev", "_")
+
+	envs, err := c.ListEnvironments()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{}, envs)
+
+	client.seed("/prod/db/host", "a")
+	client.seed("/dev/db/host", "b")
+	client.seed("/dev/api", "c")
+	client.seed("/staging/api", "d")
+	client.seed("plain", "e")
+	client.seed("/toplevel", "f")
+
+	envs, err = c.ListEnvironments()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"dev", "prod", "staging"}, envs)
+}
+
+func Test_SSMConfiguration_EnvParams(t *testing.T) {
+	for k, v := range map[string]string{
+		"AWS_ACCESS_KEY_ID":     "AKIDENV",
+		"AWS_SECRET_ACCESS_KEY": "secret",
+		"AWS_SESSION_TOKEN":     "token",
+	} {
+		old, ok := os.LookupEnv(k)
+		assert.Nil(t, os.Setenv(k, v))
+		defer func(k string) {
+			if ok {
+				os.Setenv(k, old)
+			} else {
+				os.Unsetenv(k)
+			}
+		}(k)
+	}
+
+	config, err := NewSSMConfiguration(SSMConfigurationInit{UseEnvParams: true})
+	assert.Nil(t, err)
+
+	creds, err := config.client.(*ssm.SSM).Config.Credentials.Get()
+	assert.Nil(t, err)
+	assert.Equal(t, "AKIDENV", creds.AccessKeyID)
+	assert.Equal(t, "token", creds.SessionToken)
+}
+
+func Test_SSMConfiguration_EnvParamsProfile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "credentials")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	file := dir + "/credentials"
+	assert.Nil(t, ioutil.WriteFile(file, []byte("[ci]\naws_access_key_id = AKIDFILE\naws_secret_access_key = secret\n"), 0600))
+
+	for k, v := range map[string]string{
+		"AWS_ACCESS_KEY_ID":           "",
+		"AWS_SECRET_ACCESS_KEY":       "",
+		"AWS_SHARED_CREDENTIALS_FILE": file,
+		"AWS_PROFILE":                 "ci",
+	} {
+		old, ok := os.LookupEnv(k)
+		assert.Nil(t, os.Setenv(k, v))
+		defer func(k string) {
+			if ok {
+				os.Setenv(k, old)
+			} else {
+				os.Unsetenv(k)
+			}
+		}(k)
+	}
+
+	config, err := NewSSMConfiguration(SSMConfigurationInit{UseEnvParams: true})
+	assert.Nil(t, err)
+
+	creds, err := config.client.(*ssm.SSM).Config.Credentials.Get()
+	assert.Nil(t, err)
+	assert.Equal(t, "AKIDFILE", creds.AccessKeyID)
+}
+
+func Test_SSMConfiguration_CloseHTTPClient(t *testing.T) {
+	first, err := NewSSMConfiguration(SSMConfigurationInit{UseDefaultChain: true})
+	assert.Nil(t, err)
+	second, err := NewSSMConfiguration(SSMConfigurationInit{UseDefaultChain: true})
+	assert.Nil(t, err)
+
+	assert.NotNil(t, first.httpClient)
+	assert.NotSame(t, http.DefaultClient, first.httpClient)
+	assert.NotSame(t, first.httpClient.Transport, second.httpClient.Transport)
+	assert.Nil(t, first.Close())
+}
+
+func Test_SSMConfiguration_WithEnv(t *testing.T) {
+	client := newFakeSSM()
+	client.seed("/dev/db/host", "db.dev")
+	client.seed("/staging/db/host", "db.staging")
+	dev := NewSSMConfigurationWithClient(client, "dev", "_")
+	dev.cache = newTTLCache(time.Minute)
+	dev.httpClient = &http.Client{}
+
+	staging := dev.WithEnv("staging")
+	assert.Same(t, dev.client, staging.client)
+	assert.Nil(t, staging.httpClient)
+	assert.NotSame(t, dev.cache, staging.cache)
+	assert.Equal(t, time.Minute, staging.cache.ttl)
+
+	val, err := staging.Get("db_host")
+	assert.Nil(t, err)
+	assert.Equal(t, "db.staging", val)
+
+	val, err = dev.Get("db_host")
+	assert.Nil(t, err)
+	assert.Equal(t, "db.dev", val)
+
+	assert.Nil(t, staging.Close())
+	select {
+	case <-dev.closed:
+		t.Fatal("closing the copy closed the original")
+	default:
+	}
+}
@@ -0,0 +1,90 @@
+package goawshelpers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/aws/aws-sdk-go/service/ssm"
+)
+
+// CopyOptions changes how CopyEnvironment writes the destination parameters
+type CopyOptions struct {
+	// Overwrite replaces the keys already existing in the destination, they are skipped otherwise
+	Overwrite bool
+	// Encrypt stores String parameters as SecureString, SecureString and StringList keep their type either way
+	Encrypt bool
+	// Transform is called for every key before it is written, e.g. to validate or rewrite values
+	// Returning an error fails that key only
+	Transform func(key string, param Parameter) (string, error)
+}
+
+// CopyReport lists the sorted keys CopyEnvironment copied and skipped, failed keys are returned as KeyErrors
+type CopyReport struct {
+	Copied  []string
+	Skipped []string
+}
+
+// CopyEnvironment copies every parameter of srcEnv to dstEnv, preserving its type
+// SecureString values are always decrypted to be copied, so kms:Decrypt is needed on the source key
+// The namespace, delimiter and KMS key of the configuration are used for both environments
+func (c *SSMConfiguration) CopyEnvironment(srcEnv, dstEnv string, opts CopyOptions) (CopyReport, error) {
+	return c.CopyEnvironmentWithContext(context.Background(), srcEnv, dstEnv, opts)
+}
+
+// CopyEnvironmentWithContext is the same as CopyEnvironment with the ability to pass a context
+func (c *SSMConfiguration) CopyEnvironmentWithContext(ctx context.Context, srcEnv, dstEnv string, opts CopyOptions) (CopyReport, error) {
+	src := c.WithEnv(srcEnv)
+	src.decrypt = true
+
+	params, err := src.getDetailedByPath(ctx, src.prefix(srcEnv))
+
+	if err != nil {
+		return CopyReport{}, fmt.Errorf("error reading environment %s - %w", srcEnv, err)
+	}
+
+	keys := make([]string, 0, len(params))
+	for key := range params {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var report CopyReport
+	errs := make(KeyErrors)
+
+	for _, key := range keys {
+		param := params[key]
+		value := param.Value
+
+		if opts.Transform != nil {
+			if value, err = opts.Transform(key, param); err != nil {
+				errs[key] = fmt.Errorf("error transforming value - %w", err)
+				continue
+			}
+		}
+
+		paramType := param.Type
+		if opts.Encrypt && paramType == ssm.ParameterTypeString {
+			paramType = ssm.ParameterTypeSecureString
+		}
+
+		// types added to SSM after this package was released are copied as they are
+		err = c.put(ctx, c.pathIn(dstEnv, key), value, paramType, opts.Overwrite, PutOptions{AllowUnknownType: true})
+
+		switch {
+		case errors.Is(err, ErrParameterAlreadyExists):
+			report.Skipped = append(report.Skipped, key)
+		case err != nil:
+			errs[key] = err
+		default:
+			report.Copied = append(report.Copied, key)
+		}
+	}
+
+	if len(errs) > 0 {
+		return report, fmt.Errorf("error copying environment %s to %s - %w", srcEnv, dstEnv, errs)
+	}
+
+	return report, nil
+}
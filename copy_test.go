@@ -0,0 +1,53 @@
+package goawshelpers
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_SSMConfiguration_CopyEnvironment(t *testing.T) {
+	client := newFakeSSM()
+	client.seed("/staging/db/host", "db.staging")
+	client.seed("/staging/api/key", "secret")
+	client.params["/staging/api/key"].Type = aws.String(ssm.ParameterTypeSecureString)
+	client.seed("/staging/hosts", "a,b")
+	client.params["/staging/hosts"].Type = aws.String(ssm.ParameterTypeStringList)
+	client.seed("/prod/db/host", "db.prod")
+	c := NewSSMConfigurationWithClient(client, "dev", "_")
+
+	report, err := c.CopyEnvironment("staging", "prod", CopyOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, CopyReport{Copied: []string{"api_key", "hosts"}, Skipped: []string{"db_host"}}, report)
+	assert.Equal(t, "db.prod", *client.params["/prod/db/host"].Value)
+	assert.Equal(t, ssm.ParameterTypeSecureString, *client.params["/prod/api/key"].Type)
+	assert.Equal(t, ssm.ParameterTypeStringList, *client.params["/prod/hosts"].Type)
+	assert.True(t, *client.lastPath.WithDecryption)
+
+	report, err = c.CopyEnvironment("staging", "qa", CopyOptions{
+		Overwrite: true,
+		Encrypt:   true,
+		Transform: func(key string, param Parameter) (string, error) {
+			if key == "hosts" {
+				return "", errors.New("not allowed")
+			}
+			return strings.Replace(param.Value, "staging", "qa", 1), nil
+		},
+	})
+	assert.Equal(t, []string{"api_key", "db_host"}, report.Copied)
+
+	var errs KeyErrors
+	assert.True(t, errors.As(err, &errs))
+	assert.Len(t, errs, 1)
+	assert.Contains(t, errs["hosts"].Error(), "not allowed")
+	assert.Equal(t, "db.qa", *client.params["/qa/db/host"].Value)
+	assert.Equal(t, ssm.ParameterTypeSecureString, *client.params["/qa/db/host"].Type)
+
+	client.err = errors.New("denied")
+	_, err = c.CopyEnvironment("staging", "qa", CopyOptions{})
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,120 @@
+package goawshelpers
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// dotEnvUnescaper resolves the \n, \" and \\ escapes of double quoted values, other backslashes are kept (e.g. "C:\Users")
+var dotEnvUnescaper = strings.NewReplacer(`\\`, `\`, `\n`, "\n", `\"`, `"`)
+
+// LoadDotEnv parses a .env file into a new EnvironmentConfiguration without touching the process environment
+// Variables set in the process environment take precedence over the file when calling Get
+// Lines are KEY=VALUE pairs, optionally prefixed with export, values can be single or double quoted (with \n, \" and \\ escapes)
+// Blank lines and lines starting with # are ignored, as are # comments after unquoted values
+func LoadDotEnv(path string) (*EnvironmentConfiguration, error) {
+	values, err := parseDotEnv(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	c := NewEnvironmentConfiguration(false)
+	c.loaded = values
+
+	for k, v := range values {
+		c.Values[k] = v
+	}
+
+	return c, nil
+}
+
+// LoadDotEnvAndSetenv is the same as LoadDotEnv but also sets every variable in the process environment
+func LoadDotEnvAndSetenv(path string) (*EnvironmentConfiguration, error) {
+	values, err := parseDotEnv(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	c := NewEnvironmentConfiguration(false)
+
+	for k, v := range values {
+		if err := c.Set(k, v); err != nil {
+			return nil, err
+		}
+	}
+
+	return c, nil
+}
+
+func parseDotEnv(path string) (map[string]string, error) {
+	file, err := os.Open(path)
+
+	if err != nil {
+		return nil, fmt.Errorf("error opening env file %s - %w", path, err)
+	}
+	defer file.Close()
+
+	values := make(map[string]string)
+	scanner := bufio.NewScanner(file)
+
+	for number := 1; scanner.Scan(); number++ {
+		line := strings.TrimSpace(scanner.Text())
+
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		line = strings.TrimSpace(strings.TrimPrefix(line, "export "))
+		i := strings.Index(line, "=")
+
+		if i <= 0 {
+			return nil, fmt.Errorf("error parsing env file %s line %d - expected KEY=VALUE", path, number)
+		}
+
+		key := strings.TrimSpace(line[:i])
+		value, err := parseDotEnvValue(strings.TrimSpace(line[i+1:]))
+
+		if err != nil {
+			return nil, fmt.Errorf("error parsing env file %s line %d - %w", path, number, err)
+		}
+
+		values[key] = value
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading env file %s - %w", path, err)
+	}
+
+	return values, nil
+}
+
+func parseDotEnvValue(value string) (string, error) {
+	if value == "" {
+		return "", nil
+	}
+
+	switch value[0] {
+	case '"':
+		end := strings.LastIndex(value, `"`)
+		if end == 0 {
+			return "", fmt.Errorf("unterminated double quoted value")
+		}
+		return dotEnvUnescaper.Replace(value[1:end]), nil
+	case '\'':
+		end := strings.LastIndex(value, "'")
+		if end == 0 {
+			return "", fmt.Errorf("unterminated single quoted value")
+		}
+		return value[1:end], nil
+	}
+
+	if i := strings.Index(value, " #"); i >= 0 {
+		value = strings.TrimSpace(value[:i])
+	}
+
+	return value, nil
+}
@@ -0,0 +1,74 @@
+package goawshelpers
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testDotEnv = `# database settings
+DATABASE_URL=postgres://localhost/db
+
+export GOAWSHELPERS_DOTENV_NAME="my app"
+QUOTED='single # not a comment'
+ESCAPED="line\nbreak"
+WINDOWS_PATH="C:\Program Files\app\\data \"x\" \d+"
+PORT=5432 # inline comment
+EMPTY=
+`
+
+func writeDotEnv(t *testing.T, content string) string {
+	dir, err := ioutil.TempDir("", "dotenv")
+	assert.Nil(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	path := filepath.Join(dir, ".env")
+	assert.Nil(t, ioutil.WriteFile(path, []byte(content), 0600))
+
+	return path
+}
+
+func Test_LoadDotEnv(t *testing.T) {
+	c, err := LoadDotEnv(writeDotEnv(t, testDotEnv))
+	assert.Nil(t, err)
+
+	assert.Equal(t, map[string]string{
+		"DATABASE_URL":             "postgres://localhost/db",
+		"GOAWSHELPERS_DOTENV_NAME": "my app",
+		"QUOTED":                   "single # not a comment",
+		"ESCAPED":                  "line\nbreak",
+		"WINDOWS_PATH":             `C:\Program Files\app\data "x" \d+`,
+		"PORT":                     "5432",
+		"EMPTY":                    "",
+	}, c.Values)
+
+	val, err := c.Get("GOAWSHELPERS_DOTENV_NAME")
+	assert.Nil(t, err)
+	assert.Equal(t, "my app", val)
+	assert.Equal(t, "", os.Getenv("GOAWSHELPERS_DOTENV_NAME"))
+
+	_, err = LoadDotEnv(writeDotEnv(t, "NOT A PAIR"))
+	assert.Contains(t, err.Error(), "line 1")
+
+	_, err = LoadDotEnv("/does/not/exist")
+	assert.NotNil(t, err)
+}
+
+func Test_LoadDotEnvAndSetenv(t *testing.T) {
+	defer os.Unsetenv("GOAWSHELPERS_DOTENV_NAME")
+
+	c, err := LoadDotEnvAndSetenv(writeDotEnv(t, testDotEnv))
+	assert.Nil(t, err)
+	assert.Equal(t, "my app", os.Getenv("GOAWSHELPERS_DOTENV_NAME"))
+	assert.Equal(t, "5432", c.Values["PORT"])
+
+	os.Unsetenv("PORT")
+	os.Unsetenv("DATABASE_URL")
+	os.Unsetenv("QUOTED")
+	os.Unsetenv("ESCAPED")
+	os.Unsetenv("WINDOWS_PATH")
+	os.Unsetenv("EMPTY")
+}
@@ -0,0 +1,25 @@
+package goawshelpers
+
+import "log"
+
+// PlannedOperation is a write that was skipped because of DryRun
+type PlannedOperation struct {
+	// Op is one of create, set, delete, label or unlabel
+	Op string
+	// Name is the full parameter name
+	Name string
+	// Value and Type are empty for delete, Value holds the comma separated labels for label and unlabel
+	Value string
+	Type  string
+}
+
+// plan records an operation skipped in dry run mode, it is logged when no sink is set
+// Values are never logged as they may be secrets
+func (c *SSMConfiguration) plan(op PlannedOperation) {
+	if c.dryRunSink != nil {
+		c.dryRunSink(op)
+		return
+	}
+
+	log.Printf("goawshelpers: dry run - %s %s", op.Op, op.Name)
+}
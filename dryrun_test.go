@@ -0,0 +1,46 @@
+package goawshelpers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_SSMConfiguration_DryRun(t *testing.T) {
+	var planned []PlannedOperation
+
+	config, err := NewSSMConfiguration(SSMConfigurationInit{
+		AwsAccessKey:       "key",
+		AwsSecretAccessKey: "secret",
+		DryRun:             true,
+		DryRunSink:         func(op PlannedOperation) { planned = append(planned, op) },
+	})
+	assert.Nil(t, err)
+	assert.True(t, config.dryRun)
+
+	client := newFakeSSM()
+	client.seed("/dev/old/a", "1")
+	client.seed("/dev/old/b", "2")
+
+	c := NewSSMConfigurationWithClient(client, "dev", "_")
+	c.dryRun = config.dryRun
+	c.dryRunSink = config.dryRunSink
+
+	assert.Nil(t, c.Create("new", "value"))
+	assert.Nil(t, c.Set("old_a", "changed"))
+	assert.Nil(t, c.Delete("old_b"))
+	assert.Nil(t, c.DeletePrefix("old"))
+
+	assert.Equal(t, []PlannedOperation{
+		{Op: "create", Name: "/dev/new", Value: "value", Type: "String"},
+		{Op: "set", Name: "/dev/old/a", Value: "changed", Type: "String"},
+		{Op: "delete", Name: "/dev/old/b"},
+		{Op: "delete", Name: "/dev/old/a"},
+		{Op: "delete", Name: "/dev/old/b"},
+	}, planned)
+
+	assert.Nil(t, client.lastPut)
+	val, err := c.Get("old_a")
+	assert.Nil(t, err)
+	assert.Equal(t, "1", val)
+}
@@ -0,0 +1,193 @@
+package goawshelpers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+const (
+	dynamoEnvAttribute   = "env"
+	dynamoKeyAttribute   = "key"
+	dynamoValueAttribute = "value"
+)
+
+var _ Configuration = (*DynamoConfiguration)(nil)
+
+// DynamoClient is the subset of the AWS DynamoDB API used by DynamoConfiguration
+// *dynamodb.DynamoDB follows this interface, a mock can be used for testing
+type DynamoClient interface {
+	GetItemWithContext(ctx aws.Context, input *dynamodb.GetItemInput, opts ...request.Option) (*dynamodb.GetItemOutput, error)
+	PutItemWithContext(ctx aws.Context, input *dynamodb.PutItemInput, opts ...request.Option) (*dynamodb.PutItemOutput, error)
+	DeleteItemWithContext(ctx aws.Context, input *dynamodb.DeleteItemInput, opts ...request.Option) (*dynamodb.DeleteItemOutput, error)
+	QueryPagesWithContext(ctx aws.Context, input *dynamodb.QueryInput, fn func(*dynamodb.QueryOutput, bool) bool, opts ...request.Option) error
+}
+
+// DynamoConfiguration keeps the configuration in a DynamoDB table
+// The table has env as the partition key and key as the sort key (both strings), values are stored in the value attribute
+// All methods are safe for concurrent use
+type DynamoConfiguration struct {
+	client         DynamoClient
+	env            string
+	tableName      string
+	consistentRead bool
+}
+
+// DynamoConfigurationInit helps to initialize DynamoConfiguration
+type DynamoConfigurationInit struct {
+	AWSInit
+	Env       string
+	TableName string
+	// ConsistentRead makes Get and GetEnvironment use strongly consistent reads
+	ConsistentRead bool
+}
+
+// NewDynamoConfiguration creates a new instance of DynamoConfiguration based on the passed in parameters
+func NewDynamoConfiguration(config DynamoConfigurationInit) (*DynamoConfiguration, error) {
+	sess, serviceConfig, err := newSession(config.AWSInit)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return NewDynamoConfigurationWithClient(dynamodb.New(sess, serviceConfig), config), nil
+}
+
+// NewDynamoConfigurationWithClient creates a new instance of DynamoConfiguration using an already built client
+// The AWSInit part of the config is ignored
+func NewDynamoConfigurationWithClient(client DynamoClient, config DynamoConfigurationInit) *DynamoConfiguration {
+	return &DynamoConfiguration{
+		client:         client,
+		env:            config.Env,
+		tableName:      config.TableName,
+		consistentRead: config.ConsistentRead,
+	}
+}
+
+// Create creates a new item. If the key already exists - an error is returned
+func (c *DynamoConfiguration) Create(key, value string) error {
+	_, err := c.client.PutItemWithContext(context.Background(), &dynamodb.PutItemInput{
+		TableName:                aws.String(c.tableName),
+		Item:                     c.item(key, value),
+		ConditionExpression:      aws.String("attribute_not_exists(#k)"),
+		ExpressionAttributeNames: map[string]*string{"#k": aws.String(dynamoKeyAttribute)},
+	})
+
+	if isConditionalCheckFailed(err) {
+		return fmt.Errorf("error creating a new entry with key %s - %w", key, ErrParameterAlreadyExists)
+	}
+
+	if err != nil {
+		return fmt.Errorf("error creating a new entry - %w", err)
+	}
+	return nil
+}
+
+// Set creates or updates an item
+func (c *DynamoConfiguration) Set(key, value string) error {
+	_, err := c.client.PutItemWithContext(context.Background(), &dynamodb.PutItemInput{
+		TableName: aws.String(c.tableName),
+		Item:      c.item(key, value),
+	})
+
+	if err != nil {
+		return fmt.Errorf("error setting an entry with key %s - %w", key, err)
+	}
+	return nil
+}
+
+// Delete deletes an item
+func (c *DynamoConfiguration) Delete(key string) error {
+	_, err := c.client.DeleteItemWithContext(context.Background(), &dynamodb.DeleteItemInput{
+		TableName:                aws.String(c.tableName),
+		Key:                      c.key(key),
+		ConditionExpression:      aws.String("attribute_exists(#k)"),
+		ExpressionAttributeNames: map[string]*string{"#k": aws.String(dynamoKeyAttribute)},
+	})
+
+	if isConditionalCheckFailed(err) {
+		return fmt.Errorf("error deleting key %s - %w", key, ErrParameterNotFound)
+	}
+
+	if err != nil {
+		return fmt.Errorf("error deleting key %s - %w", key, err)
+	}
+	return nil
+}
+
+// Get returns the value of an item
+func (c *DynamoConfiguration) Get(key string) (string, error) {
+	out, err := c.client.GetItemWithContext(context.Background(), &dynamodb.GetItemInput{
+		TableName:      aws.String(c.tableName),
+		Key:            c.key(key),
+		ConsistentRead: aws.Bool(c.consistentRead),
+	})
+
+	if err != nil {
+		return "", fmt.Errorf("error retrieving key %s - %w", key, err)
+	}
+
+	if len(out.Item) == 0 {
+		return "", fmt.Errorf("error retrieving key %s - %w", key, ErrParameterNotFound)
+	}
+
+	value, ok := out.Item[dynamoValueAttribute]
+
+	if !ok || value.S == nil {
+		return "", fmt.Errorf("error retrieving key %s - item has no string %s attribute", key, dynamoValueAttribute)
+	}
+
+	return *value.S, nil
+}
+
+// GetEnvironment returns all the items of the environment
+func (c *DynamoConfiguration) GetEnvironment() (map[string]string, error) {
+	values := make(map[string]string)
+
+	err := c.client.QueryPagesWithContext(context.Background(), &dynamodb.QueryInput{
+		TableName:                aws.String(c.tableName),
+		KeyConditionExpression:   aws.String("#e = :env"),
+		ExpressionAttributeNames: map[string]*string{"#e": aws.String(dynamoEnvAttribute)},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":env": {S: aws.String(c.env)},
+		},
+		ConsistentRead: aws.Bool(c.consistentRead),
+	}, func(page *dynamodb.QueryOutput, lastPage bool) bool {
+		for _, item := range page.Items {
+			key, value := item[dynamoKeyAttribute], item[dynamoValueAttribute]
+			if key != nil && key.S != nil && value != nil && value.S != nil {
+				values[*key.S] = *value.S
+			}
+		}
+		return !lastPage
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving environment - %w", err)
+	}
+
+	return values, nil
+}
+
+func (c *DynamoConfiguration) key(key string) map[string]*dynamodb.AttributeValue {
+	return map[string]*dynamodb.AttributeValue{
+		dynamoEnvAttribute: {S: aws.String(c.env)},
+		dynamoKeyAttribute: {S: aws.String(key)},
+	}
+}
+
+func (c *DynamoConfiguration) item(key, value string) map[string]*dynamodb.AttributeValue {
+	item := c.key(key)
+	item[dynamoValueAttribute] = &dynamodb.AttributeValue{S: aws.String(value)}
+	return item
+}
+
+func isConditionalCheckFailed(err error) bool {
+	var aerr awserr.Error
+	return errors.As(err, &aerr) && aerr.Code() == dynamodb.ErrCodeConditionalCheckFailedException
+}
@@ -0,0 +1,133 @@
+package goawshelpers
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeDynamo is an in-memory DynamoClient used by the tests, it only understands the expressions DynamoConfiguration uses
+type fakeDynamo struct {
+	dynamodbiface.DynamoDBAPI
+
+	mu    sync.Mutex
+	items map[string]map[string]string
+}
+
+func newFakeDynamo() *fakeDynamo {
+	return &fakeDynamo{items: make(map[string]map[string]string)}
+}
+
+func (f *fakeDynamo) GetItemWithContext(ctx aws.Context, input *dynamodb.GetItemInput, opts ...request.Option) (*dynamodb.GetItemOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	env, key := *input.Key["env"].S, *input.Key["key"].S
+	value, ok := f.items[env][key]
+	if !ok {
+		return &dynamodb.GetItemOutput{}, nil
+	}
+
+	return &dynamodb.GetItemOutput{Item: map[string]*dynamodb.AttributeValue{
+		"env":   {S: aws.String(env)},
+		"key":   {S: aws.String(key)},
+		"value": {S: aws.String(value)},
+	}}, nil
+}
+
+func (f *fakeDynamo) PutItemWithContext(ctx aws.Context, input *dynamodb.PutItemInput, opts ...request.Option) (*dynamodb.PutItemOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	env, key := *input.Item["env"].S, *input.Item["key"].S
+	if _, ok := f.items[env][key]; ok && aws.StringValue(input.ConditionExpression) == "attribute_not_exists(#k)" {
+		return nil, awserr.New(dynamodb.ErrCodeConditionalCheckFailedException, "the conditional request failed", nil)
+	}
+
+	if f.items[env] == nil {
+		f.items[env] = make(map[string]string)
+	}
+	f.items[env][key] = *input.Item["value"].S
+
+	return &dynamodb.PutItemOutput{}, nil
+}
+
+func (f *fakeDynamo) DeleteItemWithContext(ctx aws.Context, input *dynamodb.DeleteItemInput, opts ...request.Option) (*dynamodb.DeleteItemOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	env, key := *input.Key["env"].S, *input.Key["key"].S
+	if _, ok := f.items[env][key]; !ok && aws.StringValue(input.ConditionExpression) == "attribute_exists(#k)" {
+		return nil, awserr.New(dynamodb.ErrCodeConditionalCheckFailedException, "the conditional request failed", nil)
+	}
+	delete(f.items[env], key)
+
+	return &dynamodb.DeleteItemOutput{}, nil
+}
+
+func (f *fakeDynamo) QueryPagesWithContext(ctx aws.Context, input *dynamodb.QueryInput, fn func(*dynamodb.QueryOutput, bool) bool, opts ...request.Option) error {
+	f.mu.Lock()
+	env := *input.ExpressionAttributeValues[":env"].S
+	keys := make([]string, 0, len(f.items[env]))
+	for key := range f.items[env] {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	items := make([]map[string]*dynamodb.AttributeValue, 0, len(keys))
+	for _, key := range keys {
+		items = append(items, map[string]*dynamodb.AttributeValue{
+			"env":   {S: aws.String(env)},
+			"key":   {S: aws.String(key)},
+			"value": {S: aws.String(f.items[env][key])},
+		})
+	}
+	f.mu.Unlock()
+
+	fn(&dynamodb.QueryOutput{Items: items}, true)
+	return nil
+}
+
+func Test_DynamoConfiguration(t *testing.T) {
+	client := newFakeDynamo()
+	c := NewDynamoConfigurationWithClient(client, DynamoConfigurationInit{Env: "dev", TableName: "config"})
+	other := NewDynamoConfigurationWithClient(client, DynamoConfigurationInit{Env: "prod", TableName: "config"})
+
+	assert.Nil(t, c.Create("db_host", "localhost"))
+	assert.True(t, errors.Is(c.Create("db_host", "other"), ErrParameterAlreadyExists))
+	assert.Nil(t, c.Set("db_port", "5432"))
+	assert.Nil(t, other.Set("db_host", "db.internal"))
+
+	val, err := c.Get("db_host")
+	assert.Nil(t, err)
+	assert.Equal(t, "localhost", val)
+
+	_, err = c.Get("missing")
+	assert.True(t, errors.Is(err, ErrParameterNotFound))
+
+	values, err := c.GetEnvironment()
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"db_host": "localhost", "db_port": "5432"}, values)
+
+	assert.Nil(t, c.Delete("db_port"))
+	assert.True(t, errors.Is(c.Delete("db_port"), ErrParameterNotFound))
+}
+
+func Test_NewDynamoConfiguration(t *testing.T) {
+	c, err := NewDynamoConfiguration(DynamoConfigurationInit{
+		AWSInit:   AWSInit{AwsAccessKey: "key", AwsSecretAccessKey: "secret", Region: "us-east-1"},
+		Env:       "dev",
+		TableName: "config",
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, "us-east-1", *c.client.(*dynamodb.DynamoDB).Config.Region)
+}
@@ -0,0 +1,53 @@
+package goawshelpers
+
+import "time"
+
+// Event describes a single operation, it is passed to a Logger
+type Event struct {
+	// Op is one of get, create, set, delete, label or unlabel
+	// or region when a session falls back to the package default region, which is then the Key
+	Op  string
+	Key string
+	// Source is the configuration that served a BiConfiguration lookup: cache, remote, env or default
+	Source   string
+	Duration time.Duration
+	// Err is the outcome, nil on success
+	Err error
+}
+
+// Logger receives an Event for every observed operation, it has to be safe for concurrent use
+type Logger interface {
+	Log(event Event)
+}
+
+// LoggerFunc allows to use a plain function as a Logger
+type LoggerFunc func(event Event)
+
+// Log calls f(event)
+func (f LoggerFunc) Log(event Event) {
+	f(event)
+}
+
+// observedGetter reports every Get of the wrapped configuration to a Logger
+type observedGetter struct {
+	getter
+	source string
+	logger Logger
+}
+
+func (g observedGetter) Get(key string) (string, error) {
+	start := time.Now()
+	val, err := g.getter.Get(key)
+	g.logger.Log(Event{Op: "get", Key: key, Source: g.source, Duration: time.Since(start), Err: err})
+
+	return val, err
+}
+
+// observe reports an operation to the logger, if any
+func (c *SSMConfiguration) observe(op, key string, start time.Time, err error) {
+	if c.logger == nil {
+		return
+	}
+
+	c.logger.Log(Event{Op: op, Key: key, Duration: time.Since(start), Err: err})
+}
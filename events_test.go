@@ -0,0 +1,59 @@
+package goawshelpers
+
+import (
+	"errors"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type recordingLogger struct {
+	mu     sync.Mutex
+	events []Event
+}
+
+func (l *recordingLogger) Log(event Event) {
+	l.mu.Lock()
+	l.events = append(l.events, event)
+	l.mu.Unlock()
+}
+
+func Test_SSMConfiguration_Logger(t *testing.T) {
+	logger := &recordingLogger{}
+	c := NewSSMConfigurationWithClient(newFakeSSM(), "dev", "_")
+	c.logger = logger
+
+	assert.Nil(t, c.Set("key", "value"))
+	_, _ = c.Get("key")
+	_, _ = c.Get("missing")
+	assert.Nil(t, c.Delete("key"))
+
+	assert.Len(t, logger.events, 4)
+	assert.Equal(t, "set", logger.events[0].Op)
+	assert.Equal(t, "get", logger.events[1].Op)
+	assert.Nil(t, logger.events[1].Err)
+	assert.True(t, errors.Is(logger.events[2].Err, ErrParameterNotFound))
+	assert.Equal(t, "missing", logger.events[2].Key)
+	assert.Equal(t, "delete", logger.events[3].Op)
+}
+
+func Test_BiConfiguration_Logger(t *testing.T) {
+	var events []Event
+
+	b := NewBiConfigurationWith(nil, NewMemoryConfiguration(nil))
+	b.Logger = LoggerFunc(func(event Event) { events = append(events, event) })
+	assert.Nil(t, os.Setenv("GOAWSHELPERS_LOGGED", "value"))
+	defer os.Unsetenv("GOAWSHELPERS_LOGGED")
+
+	_, _ = b.Get("GOAWSHELPERS_LOGGED")
+	_, _ = b.Get("GOAWSHELPERS_LOGGED")
+
+	assert.Len(t, events, 3)
+	assert.Equal(t, "remote", events[0].Source)
+	assert.True(t, errors.Is(events[0].Err, ErrParameterNotFound))
+	assert.Equal(t, "env", events[1].Source)
+	assert.Nil(t, events[1].Err)
+	assert.Equal(t, "cache", events[2].Source)
+}
@@ -0,0 +1,416 @@
+package goawshelpers
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/aws/aws-sdk-go/service/ssm/ssmiface"
+)
+
+// fakeSSM is an in-memory SSMClient used by the tests
+type fakeSSM struct {
+	ssmiface.SSMAPI
+
+	mu       sync.Mutex
+	params   map[string]*ssm.Parameter
+	history  map[string][]*ssm.ParameterHistory
+	tags     map[string]map[string]string
+	patterns map[string]string
+	inputs   map[string]*ssm.PutParameterInput
+	err      error
+	// denied parameters fail with AccessDeniedException, like SecureStrings without kms:Decrypt
+	denied map[string]bool
+
+	lastGet  *ssm.GetParameterInput
+	lastPut  *ssm.PutParameterInput
+	lastPath *ssm.GetParametersByPathInput
+}
+
+func newFakeSSM() *fakeSSM {
+	return &fakeSSM{
+		params:   make(map[string]*ssm.Parameter),
+		history:  make(map[string][]*ssm.ParameterHistory),
+		tags:     make(map[string]map[string]string),
+		patterns: make(map[string]string),
+		inputs:   make(map[string]*ssm.PutParameterInput),
+	}
+}
+
+func (f *fakeSSM) seed(name, value string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.params[name] = &ssm.Parameter{
+		Name:             aws.String(name),
+		Value:            aws.String(value),
+		Type:             aws.String(ssm.ParameterTypeString),
+		Version:          aws.Int64(1),
+		LastModifiedDate: aws.Time(time.Now()),
+	}
+}
+
+func (f *fakeSSM) GetParameterWithContext(ctx aws.Context, input *ssm.GetParameterInput, opts ...request.Option) (*ssm.GetParameterOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.lastGet = input
+	if f.err != nil {
+		return nil, f.err
+	}
+
+	// selectors only keep the latest version around
+	name := *input.Name
+	if i := strings.Index(name, ":"); i >= 0 {
+		name = name[:i]
+	}
+
+	if f.denied[name] {
+		return nil, awserr.New("AccessDeniedException", "access denied", nil)
+	}
+
+	param, ok := f.params[name]
+	if !ok {
+		return nil, awserr.New(ssm.ErrCodeParameterNotFound, "parameter not found", nil)
+	}
+
+	return &ssm.GetParameterOutput{Parameter: param}, nil
+}
+
+func (f *fakeSSM) GetParametersWithContext(ctx aws.Context, input *ssm.GetParametersInput, opts ...request.Option) (*ssm.GetParametersOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.err != nil {
+		return nil, f.err
+	}
+
+	out := &ssm.GetParametersOutput{}
+	for _, name := range input.Names {
+		if f.denied[*name] {
+			return nil, awserr.New("AccessDeniedException", "access denied", nil)
+		}
+		if param, ok := f.params[*name]; ok {
+			out.Parameters = append(out.Parameters, param)
+		} else {
+			out.InvalidParameters = append(out.InvalidParameters, name)
+		}
+	}
+
+	return out, nil
+}
+
+func (f *fakeSSM) PutParameterWithContext(ctx aws.Context, input *ssm.PutParameterInput, opts ...request.Option) (*ssm.PutParameterOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.lastPut = input
+	if f.err != nil {
+		return nil, f.err
+	}
+
+	if len(input.Tags) > 0 && aws.BoolValue(input.Overwrite) {
+		return nil, awserr.New("ValidationException", "tags and overwrite can't be used together", nil)
+	}
+
+	pattern := f.patterns[*input.Name]
+	if input.AllowedPattern != nil {
+		pattern = *input.AllowedPattern
+	}
+	if pattern != "" && !regexp.MustCompile(pattern).MatchString(*input.Value) {
+		return nil, awserr.New(ssm.ErrCodeParameterPatternMismatchException, fmt.Sprintf("Parameter value, %s, failed to satisfy constraint: Member must satisfy regular expression pattern: %s", *input.Value, pattern), nil)
+	}
+
+	version := int64(1)
+	if existing, ok := f.params[*input.Name]; ok {
+		if !aws.BoolValue(input.Overwrite) {
+			return nil, awserr.New(ssm.ErrCodeParameterAlreadyExists, "parameter already exists", nil)
+		}
+		version = *existing.Version + 1
+	}
+	f.addTags(*input.Name, input.Tags)
+	f.patterns[*input.Name] = pattern
+	f.inputs[*input.Name] = input
+
+	f.params[*input.Name] = &ssm.Parameter{
+		Name:             input.Name,
+		Value:            input.Value,
+		Type:             input.Type,
+		Version:          aws.Int64(version),
+		LastModifiedDate: aws.Time(time.Now()),
+	}
+	f.history[*input.Name] = append(f.history[*input.Name], &ssm.ParameterHistory{
+		Name:             input.Name,
+		Value:            input.Value,
+		Type:             input.Type,
+		Version:          aws.Int64(version),
+		LastModifiedDate: aws.Time(time.Now()),
+		LastModifiedUser: aws.String("arn:aws:iam::123456789012:user/test"),
+	})
+
+	return &ssm.PutParameterOutput{Version: aws.Int64(version)}, nil
+}
+
+func (f *fakeSSM) AddTagsToResourceWithContext(ctx aws.Context, input *ssm.AddTagsToResourceInput, opts ...request.Option) (*ssm.AddTagsToResourceOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.err != nil {
+		return nil, f.err
+	}
+
+	f.addTags(*input.ResourceId, input.Tags)
+
+	return &ssm.AddTagsToResourceOutput{}, nil
+}
+
+func (f *fakeSSM) addTags(name string, tags []*ssm.Tag) {
+	if len(tags) == 0 {
+		return
+	}
+	if f.tags[name] == nil {
+		f.tags[name] = make(map[string]string)
+	}
+	for _, tag := range tags {
+		f.tags[name][*tag.Key] = *tag.Value
+	}
+}
+
+func (f *fakeSSM) DeleteParameterWithContext(ctx aws.Context, input *ssm.DeleteParameterInput, opts ...request.Option) (*ssm.DeleteParameterOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.err != nil {
+		return nil, f.err
+	}
+
+	if _, ok := f.params[*input.Name]; !ok {
+		return nil, awserr.New(ssm.ErrCodeParameterNotFound, "parameter not found", nil)
+	}
+	delete(f.params, *input.Name)
+
+	return &ssm.DeleteParameterOutput{}, nil
+}
+
+func (f *fakeSSM) DeleteParametersWithContext(ctx aws.Context, input *ssm.DeleteParametersInput, opts ...request.Option) (*ssm.DeleteParametersOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.err != nil {
+		return nil, f.err
+	}
+
+	out := &ssm.DeleteParametersOutput{}
+	for _, name := range input.Names {
+		if _, ok := f.params[*name]; ok {
+			delete(f.params, *name)
+			out.DeletedParameters = append(out.DeletedParameters, name)
+		} else {
+			out.InvalidParameters = append(out.InvalidParameters, name)
+		}
+	}
+
+	return out, nil
+}
+
+func (f *fakeSSM) GetParameterHistoryPagesWithContext(ctx aws.Context, input *ssm.GetParameterHistoryInput, fn func(*ssm.GetParameterHistoryOutput, bool) bool, opts ...request.Option) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.err != nil {
+		return f.err
+	}
+
+	history, ok := f.history[*input.Name]
+	if !ok {
+		return awserr.New(ssm.ErrCodeParameterNotFound, "parameter not found", nil)
+	}
+
+	// one entry per page to exercise paging
+	for i, entry := range history {
+		lastPage := i == len(history)-1
+		if !fn(&ssm.GetParameterHistoryOutput{Parameters: []*ssm.ParameterHistory{entry}}, lastPage) {
+			break
+		}
+	}
+
+	return nil
+}
+
+func (f *fakeSSM) GetParametersByPathPagesWithContext(ctx aws.Context, input *ssm.GetParametersByPathInput, fn func(*ssm.GetParametersByPathOutput, bool) bool, opts ...request.Option) error {
+	f.mu.Lock()
+	f.lastPath = input
+	if f.err != nil {
+		f.mu.Unlock()
+		return f.err
+	}
+
+	var params []*ssm.Parameter
+	for name, param := range f.params {
+		if !strings.HasPrefix(name, *input.Path) {
+			continue
+		}
+		if !aws.BoolValue(input.Recursive) && strings.Contains(strings.TrimPrefix(name, *input.Path), "/") {
+			continue
+		}
+		params = append(params, param)
+	}
+	f.mu.Unlock()
+
+	sort.Slice(params, func(i, j int) bool { return *params[i].Name < *params[j].Name })
+
+	// pages of 10 like the real API
+	for start := 0; start < len(params) || start == 0; start += 10 {
+		end := start + 10
+		if end > len(params) {
+			end = len(params)
+		}
+		lastPage := end == len(params)
+		if !fn(&ssm.GetParametersByPathOutput{Parameters: params[start:end]}, lastPage) || lastPage {
+			break
+		}
+	}
+
+	return nil
+}
+
+func (f *fakeSSM) DescribeParametersPagesWithContext(ctx aws.Context, input *ssm.DescribeParametersInput, fn func(*ssm.DescribeParametersOutput, bool) bool, opts ...request.Option) error {
+	f.mu.Lock()
+	if f.err != nil {
+		f.mu.Unlock()
+		return f.err
+	}
+
+	prefix, recursive, exact := "", true, ""
+	for _, filter := range input.ParameterFilters {
+		switch *filter.Key {
+		case "Path":
+			prefix = *filter.Values[0] + "/"
+			recursive = aws.StringValue(filter.Option) != "OneLevel"
+		case "Name":
+			exact = *filter.Values[0]
+		}
+	}
+
+	var params []*ssm.ParameterMetadata
+	for name, param := range f.params {
+		if !recursive && strings.Contains(strings.TrimPrefix(name, prefix), "/") {
+			continue
+		}
+		if exact != "" && name != exact {
+			continue
+		}
+		if !strings.HasPrefix(name, prefix) {
+			continue
+		}
+
+		metadata := &ssm.ParameterMetadata{Name: aws.String(name), Type: param.Type, Version: param.Version}
+		if pattern := f.patterns[name]; pattern != "" {
+			metadata.AllowedPattern = aws.String(pattern)
+		}
+		if put, ok := f.inputs[name]; ok {
+			metadata.Description = put.Description
+			metadata.Tier = put.Tier
+			metadata.KeyId = put.KeyId
+			if put.Policies != nil {
+				metadata.Policies = []*ssm.ParameterInlinePolicy{{PolicyText: put.Policies, PolicyType: aws.String("Expiration")}}
+			}
+		}
+		params = append(params, metadata)
+	}
+	f.mu.Unlock()
+
+	sort.Slice(params, func(i, j int) bool { return *params[i].Name < *params[j].Name })
+	fn(&ssm.DescribeParametersOutput{Parameters: params}, true)
+
+	return nil
+}
+
+func (f *fakeSSM) LabelParameterVersionWithContext(ctx aws.Context, input *ssm.LabelParameterVersionInput, opts ...request.Option) (*ssm.LabelParameterVersionOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.err != nil {
+		return nil, f.err
+	}
+
+	history, ok := f.history[*input.Name]
+	if !ok {
+		return nil, awserr.New(ssm.ErrCodeParameterNotFound, "parameter not found", nil)
+	}
+
+	version := aws.Int64Value(input.ParameterVersion)
+	if version == 0 {
+		version = int64(len(history))
+	}
+	if version < 1 || version > int64(len(history)) {
+		return nil, awserr.New(ssm.ErrCodeParameterVersionNotFound, "parameter version not found", nil)
+	}
+
+	out := &ssm.LabelParameterVersionOutput{ParameterVersion: aws.Int64(version)}
+	for _, label := range aws.StringValueSlice(input.Labels) {
+		if label == "" || (label[0] >= '0' && label[0] <= '9') || strings.HasPrefix(label, "aws") || strings.HasPrefix(label, "ssm") {
+			out.InvalidLabels = append(out.InvalidLabels, aws.String(label))
+			continue
+		}
+
+		// a label points to a single version, attaching it moves it
+		for _, entry := range history {
+			entry.Labels = removeLabel(entry.Labels, label)
+		}
+		history[version-1].Labels = append(history[version-1].Labels, aws.String(label))
+	}
+
+	return out, nil
+}
+
+func (f *fakeSSM) UnlabelParameterVersionWithContext(ctx aws.Context, input *ssm.UnlabelParameterVersionInput, opts ...request.Option) (*ssm.UnlabelParameterVersionOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.err != nil {
+		return nil, f.err
+	}
+
+	history, ok := f.history[*input.Name]
+	if !ok {
+		return nil, awserr.New(ssm.ErrCodeParameterNotFound, "parameter not found", nil)
+	}
+
+	version := aws.Int64Value(input.ParameterVersion)
+	if version < 1 || version > int64(len(history)) {
+		return nil, awserr.New(ssm.ErrCodeParameterVersionNotFound, "parameter version not found", nil)
+	}
+
+	out := &ssm.UnlabelParameterVersionOutput{}
+	entry := history[version-1]
+	for _, label := range aws.StringValueSlice(input.Labels) {
+		remaining := removeLabel(entry.Labels, label)
+		if len(remaining) == len(entry.Labels) {
+			out.InvalidLabels = append(out.InvalidLabels, aws.String(label))
+			continue
+		}
+
+		entry.Labels = remaining
+		out.RemovedLabels = append(out.RemovedLabels, aws.String(label))
+	}
+
+	return out, nil
+}
+
+func removeLabel(labels []*string, label string) []*string {
+	var kept []*string
+	for _, l := range labels {
+		if *l != label {
+			kept = append(kept, l)
+		}
+	}
+	return kept
+}
@@ -3,6 +3,8 @@ module github.com/meilirobots/goawshelpers
 go 1.14
 
 require (
-	github.com/aws/aws-sdk-go v1.36.31
+	github.com/aws/aws-sdk-go v1.44.0
 	github.com/stretchr/testify v1.7.0
+	golang.org/x/sync v0.0.0-20201207232520-09787c993a3a
+	gopkg.in/yaml.v3 v3.0.1
 )
@@ -0,0 +1,57 @@
+package goawshelpers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/ssm"
+)
+
+// ErrAccessDenied is returned (wrapped) by Ping when the credentials are missing, invalid, expired or lack permissions
+var ErrAccessDenied = errors.New("access denied")
+
+// ErrUnreachable is returned (wrapped) by Ping when the SSM endpoint can't be reached, e.g. a wrong region or no network
+var ErrUnreachable = errors.New("endpoint unreachable")
+
+// authErrorCodes are the error codes AWS answers with when the caller can't be authenticated or authorized
+var authErrorCodes = map[string]bool{
+	"AccessDeniedException":       true,
+	"UnrecognizedClientException": true,
+	"InvalidClientTokenId":        true,
+	"InvalidSignatureException":   true,
+	"SignatureDoesNotMatch":       true,
+	"ExpiredTokenException":       true,
+	"ExpiredToken":                true,
+	"NoCredentialProviders":       true,
+}
+
+// Ping checks that SSM is reachable and the credentials are accepted with a single cheap read-only call
+// Failures wrap ErrAccessDenied or ErrUnreachable when they can be told apart, which makes it usable as a readiness probe
+// The call is DescribeParameters, so ssm:DescribeParameters is needed on top of the permissions the reads need
+func (c *SSMConfiguration) Ping(ctx context.Context) error {
+	err := c.client.DescribeParametersPagesWithContext(ctx, &ssm.DescribeParametersInput{
+		MaxResults: aws.Int64(1),
+	}, func(page *ssm.DescribeParametersOutput, lastPage bool) bool {
+		return false
+	})
+
+	if err == nil {
+		return nil
+	}
+
+	var aerr awserr.Error
+	if errors.As(err, &aerr) {
+		switch {
+		case authErrorCodes[aerr.Code()]:
+			return fmt.Errorf("error pinging SSM - %w", &awsError{sentinel: ErrAccessDenied, err: err})
+		case aerr.Code() == request.ErrCodeRequestError, aerr.Code() == request.ErrCodeResponseTimeout:
+			return fmt.Errorf("error pinging SSM - %w", &awsError{sentinel: ErrUnreachable, err: err})
+		}
+	}
+
+	return fmt.Errorf("error pinging SSM - %w", err)
+}
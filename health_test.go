@@ -0,0 +1,38 @@
+package goawshelpers
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_SSMConfiguration_Ping(t *testing.T) {
+	client := newFakeSSM()
+	c := NewSSMConfigurationWithClient(client, "dev", "_")
+
+	assert.Nil(t, c.Ping(context.Background()))
+
+	client.err = awserr.NewRequestFailure(awserr.New("UnrecognizedClientException", "the security token is invalid", nil), 400, "req-1")
+	err := c.Ping(context.Background())
+	assert.True(t, errors.Is(err, ErrAccessDenied))
+	assert.Contains(t, err.Error(), "the security token is invalid")
+
+	var failure awserr.RequestFailure
+	assert.True(t, errors.As(err, &failure))
+	assert.Equal(t, "req-1", failure.RequestID())
+
+	client.err = awserr.New(request.ErrCodeRequestError, "send request failed", errors.New("no such host"))
+	err = c.Ping(context.Background())
+	assert.True(t, errors.Is(err, ErrUnreachable))
+	assert.Equal(t, "error pinging SSM - endpoint unreachable - RequestError: send request failed\ncaused by: no such host", err.Error())
+
+	client.err = errors.New("other")
+	err = c.Ping(context.Background())
+	assert.NotNil(t, err)
+	assert.False(t, errors.Is(err, ErrAccessDenied))
+	assert.False(t, errors.Is(err, ErrUnreachable))
+}
@@ -0,0 +1,386 @@
+package goawshelpers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/ssm"
+)
+
+// GetWithDefault returns a key from the configuration or the fallback if it can not be retrieved
+// Use GetOrDefault when errors other than a missing key should not be ignored
+func GetWithDefault(c Configuration, key, fallback string) string {
+	val, _ := GetOrDefault(c, key, fallback)
+	return val
+}
+
+// GetOrDefault returns a key from the configuration or the fallback if the key does not exist
+// Any other error is returned along with the fallback
+func GetOrDefault(c Configuration, key, fallback string) (string, error) {
+	val, err := c.Get(key)
+
+	if errors.Is(err, ErrParameterNotFound) {
+		return fallback, nil
+	}
+
+	if err != nil {
+		return fallback, err
+	}
+
+	return val, nil
+}
+
+// GetInt returns a key from the configuration parsed as an int
+func GetInt(c Configuration, key string) (int, error) {
+	val, err := c.Get(key)
+
+	if err != nil {
+		return 0, err
+	}
+
+	i, err := strconv.Atoi(val)
+
+	if err != nil {
+		return 0, fmt.Errorf("error parsing key %s value %q as int - %w", key, val, err)
+	}
+
+	return i, nil
+}
+
+// GetBool returns a key from the configuration parsed as a bool
+// Accepted values are the same as for strconv.ParseBool
+func GetBool(c Configuration, key string) (bool, error) {
+	val, err := c.Get(key)
+
+	if err != nil {
+		return false, err
+	}
+
+	b, err := strconv.ParseBool(val)
+
+	if err != nil {
+		return false, fmt.Errorf("error parsing key %s value %q as bool - %w", key, val, err)
+	}
+
+	return b, nil
+}
+
+// GetFloat64 returns a key from the configuration parsed as a float64
+func GetFloat64(c Configuration, key string) (float64, error) {
+	val, err := c.Get(key)
+
+	if err != nil {
+		return 0, err
+	}
+
+	f, err := strconv.ParseFloat(val, 64)
+
+	if err != nil {
+		return 0, fmt.Errorf("error parsing key %s value %q as float - %w", key, val, err)
+	}
+
+	return f, nil
+}
+
+// GetDuration returns a key from the configuration parsed as a time.Duration (e.g. "1m30s")
+func GetDuration(c Configuration, key string) (time.Duration, error) {
+	val, err := c.Get(key)
+
+	if err != nil {
+		return 0, err
+	}
+
+	d, err := time.ParseDuration(val)
+
+	if err != nil {
+		return 0, fmt.Errorf("error parsing key %s value %q as duration - %w", key, val, err)
+	}
+
+	return d, nil
+}
+
+// GetBytes returns a key stored by SetBytes, decoding it from base64
+func GetBytes(c Configuration, key string) ([]byte, error) {
+	val, err := c.Get(key)
+
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := base64.StdEncoding.DecodeString(val)
+
+	if err != nil {
+		return nil, fmt.Errorf("error decoding key %s as base64 - %w", key, err)
+	}
+
+	return data, nil
+}
+
+// SetBytes stores binary data as a base64 string
+// Base64 grows the data by a third, so the 4KB Standard tier limit fits about 3KB of data and the 8KB Advanced one about 6KB
+func SetBytes(c Configuration, key string, data []byte) error {
+	return c.Set(key, base64.StdEncoding.EncodeToString(data))
+}
+
+const (
+	// standardValueLimit and advancedValueLimit are the maximum parameter value sizes of the SSM tiers
+	standardValueLimit = 4 * 1024
+	advancedValueLimit = 8 * 1024
+)
+
+// GetJSON decodes a key holding a JSON document into out, e.g. a whole config section stored as one parameter
+func GetJSON(c Configuration, key string, out interface{}) error {
+	val, err := c.Get(key)
+
+	if err != nil {
+		return err
+	}
+
+	if err := json.Unmarshal([]byte(val), out); err != nil {
+		return fmt.Errorf("error decoding key %s as JSON into %T - %w", key, out, err)
+	}
+
+	return nil
+}
+
+// SetJSON stores v encoded as JSON
+// For SSM the size is checked before writing: 4KB with the Standard tier, 8KB with the Advanced and Intelligent-Tiering ones
+func SetJSON(c Configuration, key string, v interface{}) error {
+	data, err := json.Marshal(v)
+
+	if err != nil {
+		return fmt.Errorf("error encoding key %s as JSON - %w", key, err)
+	}
+
+	if ssmConfig, ok := c.(*SSMConfiguration); ok {
+		limit := advancedValueLimit
+		if ssmConfig.tier == ssm.ParameterTierStandard {
+			limit = standardValueLimit
+		}
+
+		if len(data) > limit {
+			return fmt.Errorf("error setting key %s - JSON value of %d bytes exceeds the %d bytes limit of the tier", key, len(data), limit)
+		}
+	}
+
+	return c.Set(key, string(data))
+}
+
+// ExportJSON writes the whole configuration environment into w as an indented JSON object with sorted keys
+func ExportJSON(c Configuration, w io.Writer) error {
+	values, err := c.GetEnvironment()
+
+	if err != nil {
+		return fmt.Errorf("error exporting environment - %w", err)
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	// maps are encoded with sorted keys
+	if err := encoder.Encode(values); err != nil {
+		return fmt.Errorf("error encoding environment - %w", err)
+	}
+
+	return nil
+}
+
+// ImportJSON reads a JSON object of string values from r and sets every key in the configuration
+// Keys are set in sorted order, the first failure stops the import
+func ImportJSON(c Configuration, r io.Reader) error {
+	var values map[string]string
+
+	if err := json.NewDecoder(r).Decode(&values); err != nil {
+		return fmt.Errorf("error decoding environment - %w", err)
+	}
+
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if err := c.Set(key, values[key]); err != nil {
+			return fmt.Errorf("error importing environment - %w", err)
+		}
+	}
+
+	return nil
+}
+
+// SetMany sets every key of values in the configuration
+// All the keys are attempted, failures are returned as KeyErrors
+func SetMany(c Configuration, values map[string]string) error {
+	return forEachKey(values, c.Set)
+}
+
+// CreateMany creates every key of values in the configuration
+// All the keys are attempted, failures (e.g. already existing keys) are returned as KeyErrors
+func CreateMany(c Configuration, values map[string]string) error {
+	return forEachKey(values, c.Create)
+}
+
+// CreateIfNotExists creates the key unless it already exists, in which case the existing value is left as is
+// created reports whether the key was created, errors other than ErrParameterAlreadyExists are returned
+func CreateIfNotExists(c Configuration, key, value string) (created bool, err error) {
+	err = c.Create(key, value)
+
+	if errors.Is(err, ErrParameterAlreadyExists) {
+		return false, nil
+	}
+
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// EnsureReport lists the sorted keys EnsureMany created and the ones it left untouched because they already existed
+type EnsureReport struct {
+	Created []string
+	Skipped []string
+}
+
+// EnsureMany is CreateIfNotExists for every key of values, existing keys keep their value
+// All the keys are attempted, failures are returned as KeyErrors and are part of neither list of the report
+func EnsureMany(c Configuration, values map[string]string) (EnsureReport, error) {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var report EnsureReport
+	errs := make(KeyErrors)
+
+	for _, key := range keys {
+		created, err := CreateIfNotExists(c, key, values[key])
+
+		switch {
+		case err != nil:
+			errs[key] = err
+		case created:
+			report.Created = append(report.Created, key)
+		default:
+			report.Skipped = append(report.Skipped, key)
+		}
+	}
+
+	if len(errs) > 0 {
+		return report, errs
+	}
+
+	return report, nil
+}
+
+// SyncToSSM pushes every value known to src (read, set or loaded from a .env file) into dst, under the dst env
+// Keys are mapped with the dst delimiter, e.g. DATABASE_URL lands at /env/database/url with the _ delimiter
+// Existing parameters are only replaced when overwrite is set, otherwise they are reported with ErrParameterAlreadyExists
+// Every key is attempted, results holds nil for the synced keys and the error for the failed ones
+func SyncToSSM(src *EnvironmentConfiguration, dst *SSMConfiguration, overwrite bool) (results map[string]error) {
+	// reading the cached values of an EnvironmentConfiguration never fails
+	values, _ := src.GetEnvironment()
+	results = make(map[string]error, len(values))
+
+	write := dst.Create
+	if overwrite {
+		write = dst.Set
+	}
+
+	for key, value := range values {
+		results[key] = write(key, value)
+	}
+
+	return results
+}
+
+// ExportToOSEnv sets every key of the configuration environment as a process environment variable, e.g. for an exec'd subprocess
+// Names are uppercased with every character other than letters, digits and _ replaced by _, so db.host becomes DB_HOST
+// Variables that are already set are only replaced when overwrite is set
+// Keys mapping to the same name are reported as KeyErrors wrapping ErrKeyCollision and none of them is set
+func ExportToOSEnv(c Configuration, overwrite bool) error {
+	values, err := c.GetEnvironment()
+
+	if err != nil {
+		return fmt.Errorf("error exporting environment - %w", err)
+	}
+
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	names := make(map[string]string, len(keys))
+	errs := make(KeyErrors)
+
+	for _, key := range keys {
+		name := envName(key)
+
+		if source, ok := names[name]; ok {
+			errs[key] = fmt.Errorf("%s and %s are both exported as %s - %w", source, key, name, ErrKeyCollision)
+			errs[source] = errs[key]
+			continue
+		}
+		names[name] = key
+	}
+
+	for name, key := range names {
+		if _, ok := errs[key]; ok {
+			continue
+		}
+
+		if _, set := os.LookupEnv(name); set && !overwrite {
+			continue
+		}
+
+		if err := os.Setenv(name, values[key]); err != nil {
+			errs[key] = fmt.Errorf("error setting environmental variable %s - %w", name, err)
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+
+	return nil
+}
+
+// envName converts a key into a process environment variable name
+func envName(key string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_':
+			return r
+		}
+		return '_'
+	}, key)
+}
+
+func forEachKey(values map[string]string, fn func(key, value string) error) error {
+	errs := make(KeyErrors)
+
+	for key, value := range values {
+		if err := fn(key, value); err != nil {
+			errs[key] = err
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+
+	return nil
+}
@@ -0,0 +1,253 @@
+package goawshelpers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_TypedGetters(t *testing.T) {
+	client := newFakeSSM()
+	client.seed("/dev/port", "8080")
+	client.seed("/dev/debug", "true")
+	client.seed("/dev/ratio", "0.25")
+	client.seed("/dev/timeout", "1m30s")
+	client.seed("/dev/broken", "nope")
+	c := NewSSMConfigurationWithClient(client, "dev", "_")
+
+	i, err := GetInt(c, "port")
+	assert.Nil(t, err)
+	assert.Equal(t, 8080, i)
+
+	b, err := GetBool(c, "debug")
+	assert.Nil(t, err)
+	assert.True(t, b)
+
+	f, err := GetFloat64(c, "ratio")
+	assert.Nil(t, err)
+	assert.Equal(t, 0.25, f)
+
+	d, err := GetDuration(c, "timeout")
+	assert.Nil(t, err)
+	assert.Equal(t, 90*time.Second, d)
+
+	_, err = GetInt(c, "broken")
+	assert.Contains(t, fmt.Sprint(err), `error parsing key broken value "nope" as int`)
+
+	_, err = GetBool(c, "missing")
+	assert.True(t, errors.Is(err, ErrParameterNotFound))
+}
+
+func Test_GetWithDefault(t *testing.T) {
+	client := newFakeSSM()
+	client.seed("/dev/region", "us-east-1")
+	c := NewSSMConfigurationWithClient(client, "dev", "_")
+
+	assert.Equal(t, "us-east-1", GetWithDefault(c, "region", "eu-north-1"))
+	assert.Equal(t, "eu-north-1", GetWithDefault(c, "missing", "eu-north-1"))
+
+	val, err := GetOrDefault(c, "missing", "fallback")
+	assert.Nil(t, err)
+	assert.Equal(t, "fallback", val)
+
+	client.err = errors.New("access denied")
+	val, err = GetOrDefault(c, "region", "fallback")
+	assert.NotNil(t, err)
+	assert.Equal(t, "fallback", val)
+	assert.Equal(t, "fallback", GetWithDefault(c, "region", "fallback"))
+}
+
+func Test_ExportImportJSON(t *testing.T) {
+	client := newFakeSSM()
+	client.seed("/dev/b", "2")
+	client.seed("/dev/a", "1")
+	c := NewSSMConfigurationWithClient(client, "dev", "_")
+
+	var buf bytes.Buffer
+	assert.Nil(t, ExportJSON(c, &buf))
+	assert.Equal(t, "{\n  \"a\": \"1\",\n  \"b\": \"2\"\n}\n", buf.String())
+
+	target := NewSSMConfigurationWithClient(newFakeSSM(), "staging", "_")
+	assert.Nil(t, ImportJSON(target, &buf))
+
+	values, err := target.GetEnvironment()
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"a": "1", "b": "2"}, values)
+
+	assert.NotNil(t, ImportJSON(target, strings.NewReader("not json")))
+}
+
+func Test_SetManyCreateMany(t *testing.T) {
+	client := newFakeSSM()
+	client.seed("/dev/existing", "old")
+	c := NewSSMConfigurationWithClient(client, "dev", "_")
+
+	assert.Nil(t, SetMany(c, map[string]string{"a": "1", "existing": "new"}))
+	val, _ := c.Get("existing")
+	assert.Equal(t, "new", val)
+
+	err := CreateMany(c, map[string]string{"b": "2", "existing": "newer", "a": "3"})
+
+	var errs KeyErrors
+	assert.True(t, errors.As(err, &errs))
+	assert.Len(t, errs, 2)
+	assert.Contains(t, errs, "a")
+	assert.Contains(t, errs, "existing")
+	assert.True(t, strings.HasPrefix(fmt.Sprint(err), "2 keys failed - a: "))
+
+	val, _ = c.Get("b")
+	assert.Equal(t, "2", val)
+}
+
+func Test_CreateIfNotExists(t *testing.T) {
+	client := newFakeSSM()
+	client.seed("/dev/existing", "old")
+	c := NewSSMConfigurationWithClient(client, "dev", "_")
+
+	created, err := CreateIfNotExists(c, "existing", "new")
+	assert.Nil(t, err)
+	assert.False(t, created)
+	val, _ := c.Get("existing")
+	assert.Equal(t, "old", val)
+
+	created, err = CreateIfNotExists(c, "fresh", "value")
+	assert.Nil(t, err)
+	assert.True(t, created)
+
+	client.err = errors.New("denied")
+	created, err = CreateIfNotExists(c, "other", "value")
+	assert.NotNil(t, err)
+	assert.False(t, created)
+}
+
+func Test_EnsureMany(t *testing.T) {
+	client := newFakeSSM()
+	client.seed("/dev/existing", "old")
+	c := NewSSMConfigurationWithClient(client, "dev", "_")
+
+	report, err := EnsureMany(c, map[string]string{"existing": "new", "b": "2", "a": "1", "bad key": "value"})
+	assert.Equal(t, []string{"a", "b"}, report.Created)
+	assert.Equal(t, []string{"existing"}, report.Skipped)
+
+	var errs KeyErrors
+	assert.True(t, errors.As(err, &errs))
+	assert.Len(t, errs, 1)
+	assert.True(t, errors.Is(errs["bad key"], ErrInvalidKey))
+
+	val, _ := c.Get("existing")
+	assert.Equal(t, "old", val)
+
+	env := NewMemoryConfiguration(map[string]string{"port": "9090"})
+	report, err = EnsureMany(env, map[string]string{"port": "8080", "host": "localhost"})
+	assert.Nil(t, err)
+	assert.Equal(t, EnsureReport{Created: []string{"host"}, Skipped: []string{"port"}}, report)
+}
+
+func Test_SetBytesGetBytes(t *testing.T) {
+	c := NewMemoryConfiguration(map[string]string{"plain": "not base64!"})
+	data := []byte{0x00, 0xde, 0xad, 0xbe, 0xef}
+
+	assert.Nil(t, SetBytes(c, "fingerprint", data))
+	raw, _ := c.Get("fingerprint")
+	assert.Equal(t, "AN6tvu8=", raw)
+
+	got, err := GetBytes(c, "fingerprint")
+	assert.Nil(t, err)
+	assert.Equal(t, data, got)
+
+	_, err = GetBytes(c, "plain")
+	assert.NotNil(t, err)
+
+	_, err = GetBytes(c, "missing")
+	assert.True(t, errors.Is(err, ErrParameterNotFound))
+}
+
+func Test_SyncToSSM(t *testing.T) {
+	client := newFakeSSM()
+	client.seed("/dev/database/url", "postgres://old")
+	c := NewSSMConfigurationWithClient(client, "dev", "_")
+
+	env := NewEnvironmentConfiguration(false)
+	env.Values["DATABASE_URL"] = "postgres://new"
+	env.Values["API_KEY"] = "secret"
+
+	results := SyncToSSM(env, c, false)
+	assert.Len(t, results, 2)
+	assert.Nil(t, results["API_KEY"])
+	assert.True(t, errors.Is(results["DATABASE_URL"], ErrParameterAlreadyExists))
+	assert.Equal(t, "secret", *client.params["/dev/api/key"].Value)
+	assert.Equal(t, "postgres://old", *client.params["/dev/database/url"].Value)
+
+	results = SyncToSSM(env, c, true)
+	assert.Equal(t, map[string]error{"DATABASE_URL": nil, "API_KEY": nil}, results)
+	assert.Equal(t, "postgres://new", *client.params["/dev/database/url"].Value)
+}
+
+func Test_GetJSONSetJSON(t *testing.T) {
+	c := NewMemoryConfiguration(map[string]string{"broken": "{"})
+
+	type section struct {
+		Host string `json:"host"`
+		Port int    `json:"port"`
+	}
+
+	assert.Nil(t, SetJSON(c, "database", section{Host: "localhost", Port: 5432}))
+	val, _ := c.Get("database")
+	assert.Equal(t, `{"host":"localhost","port":5432}`, val)
+
+	var out section
+	assert.Nil(t, GetJSON(c, "database", &out))
+	assert.Equal(t, section{Host: "localhost", Port: 5432}, out)
+
+	err := GetJSON(c, "broken", &out)
+	assert.Contains(t, fmt.Sprint(err), "error decoding key broken as JSON")
+	assert.True(t, errors.Is(GetJSON(c, "missing", &out), ErrParameterNotFound))
+	assert.NotNil(t, SetJSON(c, "invalid", make(chan int)))
+}
+
+func Test_SetJSON_sizeLimit(t *testing.T) {
+	client := newFakeSSM()
+	c := NewSSMConfigurationWithClient(client, "dev", "_")
+	large := strings.Repeat("x", 5*1024)
+
+	assert.Nil(t, SetJSON(c, "large", large))
+
+	c.tier = ssm.ParameterTierStandard
+	err := SetJSON(c, "large", large)
+	assert.Contains(t, fmt.Sprint(err), "exceeds the 4096 bytes limit")
+
+	err = SetJSON(c, "larger", strings.Repeat("x", 9*1024))
+	assert.NotNil(t, err)
+}
+
+func Test_ExportToOSEnv(t *testing.T) {
+	for _, name := range []string{"GOAWS_DB_HOST", "GOAWS_API_KEY", "GOAWS_A_B"} {
+		defer os.Unsetenv(name)
+	}
+	os.Setenv("GOAWS_API_KEY", "existing")
+
+	c := NewMemoryConfiguration(map[string]string{
+		"goaws_db.host": "localhost",
+		"goaws_api_key": "secret",
+	})
+
+	assert.Nil(t, ExportToOSEnv(c, false))
+	assert.Equal(t, "localhost", os.Getenv("GOAWS_DB_HOST"))
+	assert.Equal(t, "existing", os.Getenv("GOAWS_API_KEY"))
+
+	assert.Nil(t, ExportToOSEnv(c, true))
+	assert.Equal(t, "secret", os.Getenv("GOAWS_API_KEY"))
+
+	collision := NewMemoryConfiguration(map[string]string{"goaws_a-b": "1", "goaws_a.b": "2"})
+	err := ExportToOSEnv(collision, true)
+	assert.True(t, errors.Is(err, ErrKeyCollision))
+	_, set := os.LookupEnv("GOAWS_A_B")
+	assert.False(t, set)
+}
@@ -0,0 +1,93 @@
+package goawshelpers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ssm"
+)
+
+// ErrInvalidLabel is returned (wrapped) when SSM rejects some of the labels, e.g. ones starting with a number, aws or ssm
+var ErrInvalidLabel = errors.New("invalid label")
+
+// LabelVersion attaches the labels to a version of the key, a version of 0 labels the latest one
+// A label already attached to another version of the key is moved, which makes it usable to promote a version
+// Rejected labels are returned wrapping ErrInvalidLabel, the valid ones are still attached
+func (c *SSMConfiguration) LabelVersion(key string, version int64, labels []string) error {
+	return c.LabelVersionWithContext(context.Background(), key, version, labels)
+}
+
+// LabelVersionWithContext is the same as LabelVersion with the ability to pass a context
+func (c *SSMConfiguration) LabelVersionWithContext(ctx context.Context, key string, version int64, labels []string) error {
+	if c.dryRun {
+		c.plan(PlannedOperation{Op: "label", Name: c.path(key), Value: strings.Join(labels, ",")})
+		return nil
+	}
+
+	input := &ssm.LabelParameterVersionInput{
+		Name:   aws.String(c.path(key)),
+		Labels: aws.StringSlice(labels),
+	}
+	if version > 0 {
+		input.ParameterVersion = aws.Int64(version)
+	}
+
+	start := time.Now()
+	out, err := c.client.LabelParameterVersionWithContext(ctx, input)
+	c.observe("label", key, start, err)
+	c.uncacheLabels(key, labels)
+
+	if err != nil {
+		return fmt.Errorf("error labeling version %d of key %s - %w", version, key, translateError(err))
+	}
+
+	if len(out.InvalidLabels) > 0 {
+		return fmt.Errorf("error labeling version %d of key %s - %s - %w", version, key, strings.Join(aws.StringValueSlice(out.InvalidLabels), ", "), ErrInvalidLabel)
+	}
+
+	return nil
+}
+
+// UnlabelVersion detaches the labels from a version of the key
+// Labels that are not attached to that version are returned wrapping ErrInvalidLabel, the other ones are still detached
+func (c *SSMConfiguration) UnlabelVersion(key string, version int64, labels []string) error {
+	return c.UnlabelVersionWithContext(context.Background(), key, version, labels)
+}
+
+// UnlabelVersionWithContext is the same as UnlabelVersion with the ability to pass a context
+func (c *SSMConfiguration) UnlabelVersionWithContext(ctx context.Context, key string, version int64, labels []string) error {
+	if c.dryRun {
+		c.plan(PlannedOperation{Op: "unlabel", Name: c.path(key), Value: strings.Join(labels, ",")})
+		return nil
+	}
+
+	start := time.Now()
+	out, err := c.client.UnlabelParameterVersionWithContext(ctx, &ssm.UnlabelParameterVersionInput{
+		Name:             aws.String(c.path(key)),
+		ParameterVersion: aws.Int64(version),
+		Labels:           aws.StringSlice(labels),
+	})
+	c.observe("unlabel", key, start, err)
+	c.uncacheLabels(key, labels)
+
+	if err != nil {
+		return fmt.Errorf("error unlabeling version %d of key %s - %w", version, key, translateError(err))
+	}
+
+	if len(out.InvalidLabels) > 0 {
+		return fmt.Errorf("error unlabeling version %d of key %s - %s - %w", version, key, strings.Join(aws.StringValueSlice(out.InvalidLabels), ", "), ErrInvalidLabel)
+	}
+
+	return nil
+}
+
+// uncacheLabels drops the cached key:label reads, which may point to another version now
+func (c *SSMConfiguration) uncacheLabels(key string, labels []string) {
+	for _, label := range labels {
+		c.uncache(c.path(key + ":" + label))
+	}
+}
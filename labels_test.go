@@ -0,0 +1,77 @@
+package goawshelpers
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_SSMConfiguration_LabelVersion(t *testing.T) {
+	client := newFakeSSM()
+	c := NewSSMConfigurationWithClient(client, "dev", "_")
+
+	assert.Nil(t, c.Set("key", "v1"))
+	assert.Nil(t, c.Set("key", "v2"))
+
+	assert.Nil(t, c.LabelVersion("key", 1, []string{"production"}))
+	assert.Nil(t, c.LabelVersion("key", 0, []string{"staging"}))
+
+	history, err := c.GetHistory("key")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"staging"}, history[0].Labels)
+	assert.Equal(t, []string{"production"}, history[1].Labels)
+
+	// promoting moves the label
+	assert.Nil(t, c.LabelVersion("key", 2, []string{"production"}))
+	history, _ = c.GetHistory("key")
+	assert.Equal(t, []string{"staging", "production"}, history[0].Labels)
+	assert.Empty(t, history[1].Labels)
+
+	err = c.LabelVersion("key", 1, []string{"1st", "awsome", "qa"})
+	assert.True(t, errors.Is(err, ErrInvalidLabel))
+	assert.Equal(t, "error labeling version 1 of key key - 1st, awsome - invalid label", err.Error())
+	history, _ = c.GetHistory("key")
+	assert.Equal(t, []string{"qa"}, history[1].Labels)
+
+	assert.True(t, errors.Is(c.LabelVersion("missing", 1, []string{"qa"}), ErrParameterNotFound))
+	assert.NotNil(t, c.LabelVersion("key", 5, []string{"qa"}))
+}
+
+func Test_SSMConfiguration_UnlabelVersion(t *testing.T) {
+	client := newFakeSSM()
+	c := NewSSMConfigurationWithClient(client, "dev", "_")
+
+	assert.Nil(t, c.Set("key", "v1"))
+	assert.Nil(t, c.LabelVersion("key", 1, []string{"production", "qa"}))
+
+	assert.Nil(t, c.UnlabelVersion("key", 1, []string{"qa"}))
+	history, _ := c.GetHistory("key")
+	assert.Equal(t, []string{"production"}, history[0].Labels)
+
+	err := c.UnlabelVersion("key", 1, []string{"qa", "production"})
+	assert.True(t, errors.Is(err, ErrInvalidLabel))
+	assert.Equal(t, "error unlabeling version 1 of key key - qa - invalid label", err.Error())
+	history, _ = c.GetHistory("key")
+	assert.Empty(t, history[0].Labels)
+}
+
+func Test_SSMConfiguration_LabelVersionDryRun(t *testing.T) {
+	client := newFakeSSM()
+	c := NewSSMConfigurationWithClient(client, "dev", "_")
+	assert.Nil(t, c.Set("key", "v1"))
+
+	var planned []PlannedOperation
+	c.dryRun = true
+	c.dryRunSink = func(op PlannedOperation) { planned = append(planned, op) }
+
+	assert.Nil(t, c.LabelVersion("key", 1, []string{"production", "qa"}))
+	assert.Nil(t, c.UnlabelVersion("key", 1, []string{"qa"}))
+	assert.Equal(t, []PlannedOperation{
+		{Op: "label", Name: "/dev/key", Value: "production,qa"},
+		{Op: "unlabel", Name: "/dev/key", Value: "qa"},
+	}, planned)
+
+	history, _ := c.GetHistory("key")
+	assert.Empty(t, history[0].Labels)
+}
@@ -0,0 +1,101 @@
+package goawshelpers
+
+import (
+	"errors"
+	"fmt"
+)
+
+var _ Configuration = (*LayeredConfiguration)(nil)
+
+// LayeredConfiguration stacks configurations on top of each other
+// The first layer has the highest priority, writes go to a single writable layer
+type LayeredConfiguration struct {
+	layers   []Configuration
+	writable Configuration
+}
+
+// NewLayeredConfiguration returns a new instance of LayeredConfiguration
+// layers are ordered from the highest to the lowest priority, writable is the index of the layer Create, Set and Delete use
+func NewLayeredConfiguration(writable int, layers ...Configuration) (*LayeredConfiguration, error) {
+	if len(layers) == 0 {
+		return nil, errors.New("at least one layer is required")
+	}
+
+	if writable < 0 || writable >= len(layers) {
+		return nil, fmt.Errorf("writable layer %d out of range, %d layers provided", writable, len(layers))
+	}
+
+	for i, layer := range layers {
+		if layer == nil {
+			return nil, fmt.Errorf("layer %d is nil", i)
+		}
+	}
+
+	return &LayeredConfiguration{
+		layers:   append([]Configuration(nil), layers...),
+		writable: layers[writable],
+	}, nil
+}
+
+// Create creates the key in the writable layer
+func (c *LayeredConfiguration) Create(key, value string) error {
+	return c.writable.Create(key, value)
+}
+
+// Set sets the key in the writable layer
+func (c *LayeredConfiguration) Set(key, value string) error {
+	return c.writable.Set(key, value)
+}
+
+// Delete deletes the key from the writable layer, lower layers may still provide a value for it
+func (c *LayeredConfiguration) Delete(key string) error {
+	return c.writable.Delete(key)
+}
+
+// Get returns the value from the first layer that has the key
+// The next layer is only checked when the key was not found, other errors are returned as is
+func (c *LayeredConfiguration) Get(key string) (string, error) {
+	layers := make([]getter, len(c.layers))
+	for i, layer := range c.layers {
+		layers[i] = layer
+	}
+
+	return lookup(key, layers...)
+}
+
+// GetEnvironment merges the values of all layers, higher layers win
+func (c *LayeredConfiguration) GetEnvironment() (map[string]string, error) {
+	values := make(map[string]string)
+
+	for i := len(c.layers) - 1; i >= 0; i-- {
+		layerValues, err := c.layers[i].GetEnvironment()
+
+		if err != nil {
+			return nil, fmt.Errorf("error retrieving environment from layer %d - %w", i, err)
+		}
+
+		for k, v := range layerValues {
+			values[k] = v
+		}
+	}
+
+	return values, nil
+}
+
+// lookup returns the value from the first getter that has the key
+// Errors other than not found are returned right away, a missing key always gives the same error whatever the layers
+func lookup(key string, layers ...getter) (string, error) {
+	for _, layer := range layers {
+		val, err := layer.Get(key)
+
+		if err == nil {
+			return val, nil
+		}
+
+		if !errors.Is(err, ErrParameterNotFound) {
+			return "", err
+		}
+	}
+
+	return "", fmt.Errorf("no value with key %s - %w", key, ErrParameterNotFound)
+}
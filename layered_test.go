@@ -0,0 +1,64 @@
+package goawshelpers
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_LayeredConfiguration(t *testing.T) {
+	overrides := NewMemoryConfiguration(map[string]string{"host": "localhost"})
+	base := NewMemoryConfiguration(map[string]string{"host": "db.internal", "port": "5432"})
+
+	c, err := NewLayeredConfiguration(0, overrides, base)
+	assert.Nil(t, err)
+
+	val, err := c.Get("host")
+	assert.Nil(t, err)
+	assert.Equal(t, "localhost", val)
+
+	val, err = c.Get("port")
+	assert.Nil(t, err)
+	assert.Equal(t, "5432", val)
+
+	_, err = c.Get("missing")
+	assert.True(t, errors.Is(err, ErrParameterNotFound))
+
+	assert.Nil(t, c.Set("port", "6543"))
+	val, _ = c.Get("port")
+	assert.Equal(t, "6543", val)
+	val, _ = base.Get("port")
+	assert.Equal(t, "5432", val)
+
+	values, err := c.GetEnvironment()
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"host": "localhost", "port": "6543"}, values)
+
+	assert.Nil(t, c.Delete("host"))
+	val, _ = c.Get("host")
+	assert.Equal(t, "db.internal", val)
+}
+
+func Test_LayeredConfiguration_Errors(t *testing.T) {
+	_, err := NewLayeredConfiguration(0)
+	assert.NotNil(t, err)
+
+	_, err = NewLayeredConfiguration(1, NewMemoryConfiguration(nil))
+	assert.NotNil(t, err)
+
+	_, err = NewLayeredConfiguration(0, NewMemoryConfiguration(nil), nil)
+	assert.NotNil(t, err)
+
+	failing := newFakeSSM()
+	failing.err = errors.New("boom")
+	c, err := NewLayeredConfiguration(0,
+		NewSSMConfigurationWithClient(failing, "dev", "_"),
+		NewMemoryConfiguration(map[string]string{"key": "value"}),
+	)
+	assert.Nil(t, err)
+
+	_, err = c.Get("key")
+	assert.NotNil(t, err)
+	assert.False(t, errors.Is(err, ErrParameterNotFound))
+}
@@ -0,0 +1,90 @@
+package goawshelpers
+
+import (
+	"fmt"
+	"sync"
+)
+
+var _ Configuration = (*MemoryConfiguration)(nil)
+
+// MemoryConfiguration keeps all the values in memory, it is mainly meant as a test double
+// All methods are safe for concurrent use
+type MemoryConfiguration struct {
+	values map[string]string
+	mu     sync.RWMutex
+}
+
+// NewMemoryConfiguration returns a new instance of MemoryConfiguration seeded with a copy of values
+func NewMemoryConfiguration(values map[string]string) *MemoryConfiguration {
+	c := &MemoryConfiguration{
+		values: make(map[string]string, len(values)),
+	}
+
+	for k, v := range values {
+		c.values[k] = v
+	}
+
+	return c
+}
+
+// Create stores a new key. If the key already exists - an error is returned
+func (c *MemoryConfiguration) Create(key, value string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.values[key]; ok {
+		return fmt.Errorf("error creating a new entry with key %s - %w", key, ErrParameterAlreadyExists)
+	}
+	c.values[key] = value
+
+	return nil
+}
+
+// Set creates or updates a key
+func (c *MemoryConfiguration) Set(key, value string) error {
+	c.mu.Lock()
+	c.values[key] = value
+	c.mu.Unlock()
+
+	return nil
+}
+
+// Delete removes a key
+func (c *MemoryConfiguration) Delete(key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.values[key]; !ok {
+		return fmt.Errorf("error deleting key %s - %w", key, ErrParameterNotFound)
+	}
+	delete(c.values, key)
+
+	return nil
+}
+
+// Get returns a key
+func (c *MemoryConfiguration) Get(key string) (string, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	value, ok := c.values[key]
+
+	if !ok {
+		return "", fmt.Errorf("no value with key %s - %w", key, ErrParameterNotFound)
+	}
+
+	return value, nil
+}
+
+// GetEnvironment returns a copy of all the keys
+func (c *MemoryConfiguration) GetEnvironment() (map[string]string, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	values := make(map[string]string, len(c.values))
+	for k, v := range c.values {
+		values[k] = v
+	}
+
+	return values, nil
+}
@@ -0,0 +1,32 @@
+package goawshelpers
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_MemoryConfiguration(t *testing.T) {
+	seed := map[string]string{"existing": "value"}
+	c := NewMemoryConfiguration(seed)
+
+	assert.True(t, errors.Is(c.Create("existing", "other"), ErrParameterAlreadyExists))
+	assert.Nil(t, c.Create("new", "1"))
+	assert.Nil(t, c.Set("existing", "changed"))
+	assert.Equal(t, "value", seed["existing"])
+
+	val, err := c.Get("existing")
+	assert.Nil(t, err)
+	assert.Equal(t, "changed", val)
+
+	assert.Nil(t, c.Delete("new"))
+	assert.True(t, errors.Is(c.Delete("new"), ErrParameterNotFound))
+
+	_, err = c.Get("new")
+	assert.True(t, errors.Is(err, ErrParameterNotFound))
+
+	values, err := c.GetEnvironment()
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"existing": "changed"}, values)
+}
@@ -0,0 +1,105 @@
+package goawshelpers
+
+import (
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/ssm"
+)
+
+// MetricsRecorder is called around every SSM API call, e.g. to feed Prometheus counters and histograms
+// op is the API operation name (GetParameter, PutParameter etc.), err is the raw SDK error
+// Paginated calls are observed once for all of their pages. Implementations have to be safe for concurrent use
+type MetricsRecorder interface {
+	ObserveOp(op string, dur time.Duration, err error)
+}
+
+// recordingSSMClient reports every call of the wrapped client to a MetricsRecorder
+type recordingSSMClient struct {
+	client  SSMClient
+	metrics MetricsRecorder
+}
+
+var _ SSMClient = recordingSSMClient{}
+
+func (c recordingSSMClient) observe(op string, start time.Time, err error) {
+	c.metrics.ObserveOp(op, time.Since(start), err)
+}
+
+func (c recordingSSMClient) GetParameterWithContext(ctx aws.Context, input *ssm.GetParameterInput, opts ...request.Option) (*ssm.GetParameterOutput, error) {
+	start := time.Now()
+	out, err := c.client.GetParameterWithContext(ctx, input, opts...)
+	c.observe("GetParameter", start, err)
+	return out, err
+}
+
+func (c recordingSSMClient) GetParametersWithContext(ctx aws.Context, input *ssm.GetParametersInput, opts ...request.Option) (*ssm.GetParametersOutput, error) {
+	start := time.Now()
+	out, err := c.client.GetParametersWithContext(ctx, input, opts...)
+	c.observe("GetParameters", start, err)
+	return out, err
+}
+
+func (c recordingSSMClient) PutParameterWithContext(ctx aws.Context, input *ssm.PutParameterInput, opts ...request.Option) (*ssm.PutParameterOutput, error) {
+	start := time.Now()
+	out, err := c.client.PutParameterWithContext(ctx, input, opts...)
+	c.observe("PutParameter", start, err)
+	return out, err
+}
+
+func (c recordingSSMClient) AddTagsToResourceWithContext(ctx aws.Context, input *ssm.AddTagsToResourceInput, opts ...request.Option) (*ssm.AddTagsToResourceOutput, error) {
+	start := time.Now()
+	out, err := c.client.AddTagsToResourceWithContext(ctx, input, opts...)
+	c.observe("AddTagsToResource", start, err)
+	return out, err
+}
+
+func (c recordingSSMClient) DeleteParameterWithContext(ctx aws.Context, input *ssm.DeleteParameterInput, opts ...request.Option) (*ssm.DeleteParameterOutput, error) {
+	start := time.Now()
+	out, err := c.client.DeleteParameterWithContext(ctx, input, opts...)
+	c.observe("DeleteParameter", start, err)
+	return out, err
+}
+
+func (c recordingSSMClient) DeleteParametersWithContext(ctx aws.Context, input *ssm.DeleteParametersInput, opts ...request.Option) (*ssm.DeleteParametersOutput, error) {
+	start := time.Now()
+	out, err := c.client.DeleteParametersWithContext(ctx, input, opts...)
+	c.observe("DeleteParameters", start, err)
+	return out, err
+}
+
+func (c recordingSSMClient) GetParametersByPathPagesWithContext(ctx aws.Context, input *ssm.GetParametersByPathInput, fn func(*ssm.GetParametersByPathOutput, bool) bool, opts ...request.Option) error {
+	start := time.Now()
+	err := c.client.GetParametersByPathPagesWithContext(ctx, input, fn, opts...)
+	c.observe("GetParametersByPath", start, err)
+	return err
+}
+
+func (c recordingSSMClient) GetParameterHistoryPagesWithContext(ctx aws.Context, input *ssm.GetParameterHistoryInput, fn func(*ssm.GetParameterHistoryOutput, bool) bool, opts ...request.Option) error {
+	start := time.Now()
+	err := c.client.GetParameterHistoryPagesWithContext(ctx, input, fn, opts...)
+	c.observe("GetParameterHistory", start, err)
+	return err
+}
+
+func (c recordingSSMClient) DescribeParametersPagesWithContext(ctx aws.Context, input *ssm.DescribeParametersInput, fn func(*ssm.DescribeParametersOutput, bool) bool, opts ...request.Option) error {
+	start := time.Now()
+	err := c.client.DescribeParametersPagesWithContext(ctx, input, fn, opts...)
+	c.observe("DescribeParameters", start, err)
+	return err
+}
+
+func (c recordingSSMClient) LabelParameterVersionWithContext(ctx aws.Context, input *ssm.LabelParameterVersionInput, opts ...request.Option) (*ssm.LabelParameterVersionOutput, error) {
+	start := time.Now()
+	out, err := c.client.LabelParameterVersionWithContext(ctx, input, opts...)
+	c.observe("LabelParameterVersion", start, err)
+	return out, err
+}
+
+func (c recordingSSMClient) UnlabelParameterVersionWithContext(ctx aws.Context, input *ssm.UnlabelParameterVersionInput, opts ...request.Option) (*ssm.UnlabelParameterVersionOutput, error) {
+	start := time.Now()
+	out, err := c.client.UnlabelParameterVersionWithContext(ctx, input, opts...)
+	c.observe("UnlabelParameterVersion", start, err)
+	return out, err
+}
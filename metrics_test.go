@@ -0,0 +1,54 @@
+package goawshelpers
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type recordedOp struct {
+	op  string
+	err error
+}
+
+type fakeRecorder struct {
+	mu  sync.Mutex
+	ops []recordedOp
+}
+
+func (r *fakeRecorder) ObserveOp(op string, dur time.Duration, err error) {
+	r.mu.Lock()
+	r.ops = append(r.ops, recordedOp{op: op, err: err})
+	r.mu.Unlock()
+}
+
+func Test_SSMConfiguration_Metrics(t *testing.T) {
+	config, err := NewSSMConfiguration(SSMConfigurationInit{
+		AwsAccessKey:       "key",
+		AwsSecretAccessKey: "secret",
+		Metrics:            &fakeRecorder{},
+	})
+	assert.Nil(t, err)
+	_, ok := config.client.(recordingSSMClient)
+	assert.True(t, ok)
+
+	recorder := &fakeRecorder{}
+	client := newFakeSSM()
+	c := NewSSMConfigurationWithClient(recordingSSMClient{client: client, metrics: recorder}, "dev", "_")
+
+	assert.Nil(t, c.Set("key", "value"))
+	_, _ = c.Get("key")
+	_, _ = c.GetEnvironment()
+	client.err = errors.New("throttled")
+	_, _ = c.Get("key")
+
+	assert.Equal(t, []recordedOp{
+		{op: "PutParameter"},
+		{op: "GetParameter"},
+		{op: "GetParametersByPath"},
+		{op: "GetParameter", err: client.err},
+	}, recorder.ops)
+}
@@ -0,0 +1,130 @@
+package goawshelpers
+
+import "time"
+
+// Option configures NewSSM, every option sets the SSMConfigurationInit field of the same name
+type Option func(config *SSMConfigurationInit)
+
+// NewSSM creates a new instance of SSMConfiguration for env using functional options
+// It is equivalent to NewSSMConfiguration, the default credential chain is used unless credentials are set
+func NewSSM(env string, opts ...Option) (*SSMConfiguration, error) {
+	config := SSMConfigurationInit{Env: env, UseDefaultChain: true}
+
+	for _, opt := range opts {
+		opt(&config)
+	}
+
+	return NewSSMConfiguration(config)
+}
+
+// WithRegion sets the AWS region
+func WithRegion(region string) Option {
+	return func(config *SSMConfigurationInit) {
+		config.Region = region
+	}
+}
+
+// WithStaticCredentials uses an access key instead of the default credential chain
+func WithStaticCredentials(accessKey, secretAccessKey string) Option {
+	return func(config *SSMConfigurationInit) {
+		config.UseDefaultChain = false
+		config.UseEnvParams = false
+		config.AwsAccessKey = accessKey
+		config.AwsSecretAccessKey = secretAccessKey
+	}
+}
+
+// WithEnvCredentials reads the credentials from the standard AWS environment variables instead of the default chain
+func WithEnvCredentials() Option {
+	return func(config *SSMConfigurationInit) {
+		config.UseDefaultChain = false
+		config.UseEnvParams = true
+	}
+}
+
+// WithAssumeRole assumes the role on top of the base credentials, externalID may be empty
+func WithAssumeRole(roleArn, externalID string) Option {
+	return func(config *SSMConfigurationInit) {
+		config.AssumeRoleArn = roleArn
+		config.ExternalId = externalID
+	}
+}
+
+// WithDelimiter sets the key delimiter, _ by default
+func WithDelimiter(delimiter string) Option {
+	return func(config *SSMConfigurationInit) {
+		config.KeyDelimitor = delimiter
+	}
+}
+
+// WithNamespace scopes the configuration to /env/namespace/
+func WithNamespace(namespace string) Option {
+	return func(config *SSMConfigurationInit) {
+		config.Namespace = namespace
+	}
+}
+
+// WithSecure stores values as SecureString and decrypts them when reading
+func WithSecure() Option {
+	return func(config *SSMConfigurationInit) {
+		config.Secure = true
+	}
+}
+
+// WithKMSKey sets the KMS key used for SecureString values
+func WithKMSKey(keyID string) Option {
+	return func(config *SSMConfigurationInit) {
+		config.KmsKeyId = keyID
+	}
+}
+
+// WithTier sets the parameter tier
+func WithTier(tier string) Option {
+	return func(config *SSMConfigurationInit) {
+		config.Tier = tier
+	}
+}
+
+// WithTags sets the tags added to every written parameter
+func WithTags(tags map[string]string) Option {
+	return func(config *SSMConfigurationInit) {
+		config.Tags = tags
+	}
+}
+
+// WithRetries sets the retry count and base delay, a zero delay keeps the SDK default
+func WithRetries(maxRetries int, baseDelay time.Duration) Option {
+	return func(config *SSMConfigurationInit) {
+		config.MaxRetries = maxRetries
+		config.RetryBaseDelay = baseDelay
+	}
+}
+
+// WithEndpoint overrides the AWS endpoint, e.g. for LocalStack
+func WithEndpoint(endpoint string, disableSSL bool) Option {
+	return func(config *SSMConfigurationInit) {
+		config.Endpoint = endpoint
+		config.DisableSSL = disableSSL
+	}
+}
+
+// WithCacheTTL caches the values read by Get for ttl
+func WithCacheTTL(ttl time.Duration) Option {
+	return func(config *SSMConfigurationInit) {
+		config.CacheTTL = ttl
+	}
+}
+
+// WithLogger sets the Logger notified about every operation
+func WithLogger(logger Logger) Option {
+	return func(config *SSMConfigurationInit) {
+		config.Logger = logger
+	}
+}
+
+// WithMetrics sets the MetricsRecorder observing every API call
+func WithMetrics(metrics MetricsRecorder) Option {
+	return func(config *SSMConfigurationInit) {
+		config.Metrics = metrics
+	}
+}
@@ -0,0 +1,36 @@
+package goawshelpers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_NewSSM(t *testing.T) {
+	c, err := NewSSM("dev",
+		WithRegion("us-west-2"),
+		WithStaticCredentials("key", "secret"),
+		WithDelimiter("."),
+		WithSecure(),
+		WithKMSKey("alias/config"),
+		WithTier(ssm.ParameterTierAdvanced),
+		WithCacheTTL(time.Minute),
+	)
+
+	assert.Nil(t, err)
+	assert.Equal(t, "us-west-2", *c.client.(*ssm.SSM).Config.Region)
+	assert.Equal(t, "dev", c.env)
+	assert.Equal(t, ".", c.keyDelimitor)
+	assert.True(t, c.secure)
+	assert.Equal(t, "alias/config", c.kmsKeyID)
+	assert.Equal(t, ssm.ParameterTierAdvanced, c.tier)
+	assert.NotNil(t, c.cache)
+
+	_, err = NewSSM("dev", WithTier("premium"))
+	assert.NotNil(t, err)
+
+	_, err = NewSSM("dev", WithStaticCredentials("", ""))
+	assert.NotNil(t, err)
+}
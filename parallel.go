@@ -0,0 +1,131 @@
+package goawshelpers
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// parallelWorkers is the maximum amount of prefixes GetEnvironmentParallel fetches at the same time
+const parallelWorkers = 4
+
+// GetEnvironmentParallel is the same as GetEnvironmentByPrefix for several prefixes, fetching them concurrently
+// Parameters of different prefixes that map to the same key are reported as KeyErrors wrapping ErrKeyCollision
+func (c *SSMConfiguration) GetEnvironmentParallel(prefixes []string) (map[string]string, error) {
+	return c.GetEnvironmentParallelWithContext(context.Background(), prefixes)
+}
+
+// GetEnvironmentParallelWithContext is the same as GetEnvironmentParallel with the ability to pass a context
+// The first failing prefix cancels the remaining ones
+func (c *SSMConfiguration) GetEnvironmentParallelWithContext(ctx context.Context, prefixes []string) (map[string]string, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+		params   = make(map[string]string)
+		sem      = make(chan struct{}, parallelWorkers)
+	)
+
+	for _, prefix := range prefixes {
+		wg.Add(1)
+
+		go func(prefix string) {
+			defer wg.Done()
+
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			case <-ctx.Done():
+				return
+			}
+
+			path, namePrefix := c.prefixPath(prefix)
+			values, err := c.getPathsByPath(ctx, path)
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			if err != nil {
+				if firstErr == nil {
+					firstErr = fmt.Errorf("error retrieving parameters under %s - %w", path, err)
+					cancel()
+				}
+				return
+			}
+
+			// overlapping prefixes return the same parameters, which is not a collision
+			for name, value := range filterNames(values, namePrefix) {
+				params[name] = value
+			}
+		}(prefix)
+	}
+
+	wg.Wait()
+
+	if firstErr == nil {
+		firstErr = ctx.Err()
+	}
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+
+	return c.keysFromPaths(params)
+}
+
+// GetManyParallel returns multiple keys reading each of them separately, at most limit at the same time
+// Unlike GetMany it supports keys that can't be batched, e.g. with version or label selectors (key:3, key:Stable)
+// The first failing key (including a missing one) cancels the remaining reads and is returned without any values
+// Every read is cached and observed the same as with Get, a limit lower than 1 reads one key at a time
+func (c *SSMConfiguration) GetManyParallel(keys []string, limit int) (map[string]string, error) {
+	return c.GetManyParallelWithContext(context.Background(), keys, limit)
+}
+
+// GetManyParallelWithContext is the same as GetManyParallel with the ability to pass a context
+func (c *SSMConfiguration) GetManyParallelWithContext(ctx context.Context, keys []string, limit int) (map[string]string, error) {
+	if limit < 1 {
+		limit = 1
+	}
+
+	group, ctx := errgroup.WithContext(ctx)
+	sem := make(chan struct{}, limit)
+
+	var mu sync.Mutex
+	values := make(map[string]string, len(keys))
+
+	for _, key := range keys {
+		key := key
+
+		group.Go(func() error {
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+
+			value, err := c.GetWithContext(ctx, key)
+
+			if err != nil {
+				return err
+			}
+
+			mu.Lock()
+			values[key] = value
+			mu.Unlock()
+
+			return nil
+		})
+	}
+
+	if err := group.Wait(); err != nil {
+		return nil, fmt.Errorf("error retrieving multiple keys - %w", err)
+	}
+
+	return values, nil
+}
@@ -0,0 +1,104 @@
+package goawshelpers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_SSMConfiguration_GetEnvironmentParallel(t *testing.T) {
+	client := newFakeSSM()
+	client.seed("/dev/database/host", "db")
+	client.seed("/dev/cache/host", "redis")
+	client.seed("/dev/cache/ttl", "60")
+	client.seed("/dev/other", "skipped")
+	c := NewSSMConfigurationWithClient(client, "dev", "_")
+
+	values, err := c.GetEnvironmentParallel([]string{"database", "cache", "cache_host", "missing"})
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"database_host": "db", "cache_host": "redis", "cache_ttl": "60"}, values)
+
+	client.seed("/dev/cache/ttl_max", "120")
+	client.seed("/dev/cache/ttl/max", "90")
+	_, err = c.GetEnvironmentParallel([]string{"database", "cache_ttl"})
+	assert.Nil(t, err)
+	_, err = c.GetEnvironmentParallel([]string{"cache", "cache_ttl"})
+	assert.True(t, errors.Is(err, ErrKeyCollision))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = c.GetEnvironmentParallelWithContext(ctx, []string{"a", "b", "c", "d", "e"})
+	assert.True(t, errors.Is(err, context.Canceled))
+
+	client.err = errors.New("throttled")
+	_, err = c.GetEnvironmentParallel([]string{"database", "cache"})
+	assert.NotNil(t, err)
+}
+
+// slowSSM delays every GetParameter call and records how many of them run at the same time
+type slowSSM struct {
+	*fakeSSM
+
+	mu       sync.Mutex
+	inFlight int
+	peak     int
+}
+
+func (s *slowSSM) GetParameterWithContext(ctx aws.Context, input *ssm.GetParameterInput, opts ...request.Option) (*ssm.GetParameterOutput, error) {
+	s.mu.Lock()
+	s.inFlight++
+	if s.inFlight > s.peak {
+		s.peak = s.inFlight
+	}
+	s.mu.Unlock()
+
+	defer func() {
+		s.mu.Lock()
+		s.inFlight--
+		s.mu.Unlock()
+	}()
+
+	select {
+	case <-time.After(10 * time.Millisecond):
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	return s.fakeSSM.GetParameterWithContext(ctx, input, opts...)
+}
+
+func Test_SSMConfiguration_GetManyParallel(t *testing.T) {
+	client := &slowSSM{fakeSSM: newFakeSSM()}
+	var keys []string
+	for i := 0; i < 20; i++ {
+		key := fmt.Sprintf("key%d", i)
+		client.seed("/dev/"+key, fmt.Sprint(i))
+		keys = append(keys, key)
+	}
+	c := NewSSMConfigurationWithClient(client, "dev", "_")
+
+	values, err := c.GetManyParallel(append(keys, "key3:Stable"), 3)
+	assert.Nil(t, err)
+	assert.Len(t, values, 21)
+	assert.Equal(t, "7", values["key7"])
+	assert.Equal(t, "3", values["key3:Stable"])
+	assert.LessOrEqual(t, client.peak, 3)
+	assert.Greater(t, client.peak, 1)
+
+	_, err = c.GetManyParallel(append(keys, "missing"), 5)
+	assert.True(t, errors.Is(err, ErrParameterNotFound))
+	assert.LessOrEqual(t, client.peak, 5)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = c.GetManyParallelWithContext(ctx, keys, 2)
+	assert.True(t, errors.Is(err, context.Canceled))
+}
@@ -0,0 +1,373 @@
+package goawshelpers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"gopkg.in/yaml.v3"
+)
+
+var _ Configuration = (*S3Configuration)(nil)
+
+// ErrNotFlat is returned (wrapped) by the writes of S3Configuration when the object holds more than top level strings
+var ErrNotFlat = errors.New("object is not a flat map of strings")
+
+// S3Client is the subset of the AWS S3 API used by S3Configuration
+// *s3.S3 follows this interface, a mock can be used for testing
+type S3Client interface {
+	GetObjectWithContext(ctx aws.Context, input *s3.GetObjectInput, opts ...request.Option) (*s3.GetObjectOutput, error)
+	PutObjectWithContext(ctx aws.Context, input *s3.PutObjectInput, opts ...request.Option) (*s3.PutObjectOutput, error)
+}
+
+// S3Configuration serves the keys of a single YAML or JSON object stored in S3
+// Nested objects are flattened by joining their keys with the delimiter, e.g. {"db": {"host": "x"}} gives db_host
+// Writes rewrite the whole object with the flat keys, as JSON when the object key ends with .json and as YAML otherwise
+// so they fail with ErrNotFlat unless the object only holds top level strings without comments
+// A write only succeeds when the object was not changed since it was read (If-Match on its ETag), ErrVersionConflict is returned otherwise
+// All methods are safe for concurrent use
+type S3Configuration struct {
+	client         S3Client
+	bucket         string
+	objectKey      string
+	keyDelimitor   string
+	reloadInterval time.Duration
+	now            func() time.Time
+
+	mu       sync.Mutex
+	values   map[string]string
+	etag     string
+	loadedAt time.Time
+	// flat is whether the object can be rewritten from values without losing anything
+	flat bool
+}
+
+// S3ConfigurationInit helps to initialize S3Configuration
+type S3ConfigurationInit struct {
+	AWSInit
+	Bucket string
+	// Key is the object holding the configuration, e.g. config/dev.yaml
+	Key          string
+	KeyDelimitor string
+	// ReloadInterval is how long the loaded object is used before checking (by ETag) whether it changed
+	// The check happens on every read when zero
+	ReloadInterval time.Duration
+}
+
+// NewS3Configuration creates a new instance of S3Configuration based on the passed in parameters
+func NewS3Configuration(config S3ConfigurationInit) (*S3Configuration, error) {
+	sess, serviceConfig, err := newSession(config.AWSInit)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return NewS3ConfigurationWithClient(s3.New(sess, serviceConfig), config), nil
+}
+
+// NewS3ConfigurationWithClient creates a new instance of S3Configuration using an already built client
+// The AWSInit part of the config is ignored
+func NewS3ConfigurationWithClient(client S3Client, config S3ConfigurationInit) *S3Configuration {
+	if config.KeyDelimitor == "" {
+		config.KeyDelimitor = defaultKeyDelimitor
+	}
+
+	return &S3Configuration{
+		client:         client,
+		bucket:         config.Bucket,
+		objectKey:      config.Key,
+		keyDelimitor:   config.KeyDelimitor,
+		reloadInterval: config.ReloadInterval,
+		now:            time.Now,
+	}
+}
+
+// Create adds a new key to the object. If the key already exists - an error is returned
+func (c *S3Configuration) Create(key, value string) error {
+	return c.update(func(values map[string]string) error {
+		if _, ok := values[key]; ok {
+			return fmt.Errorf("error creating a new entry with key %s - %w", key, ErrParameterAlreadyExists)
+		}
+		values[key] = value
+		return nil
+	})
+}
+
+// Set creates or updates a key of the object
+func (c *S3Configuration) Set(key, value string) error {
+	return c.update(func(values map[string]string) error {
+		values[key] = value
+		return nil
+	})
+}
+
+// Delete removes a key from the object
+func (c *S3Configuration) Delete(key string) error {
+	return c.update(func(values map[string]string) error {
+		if _, ok := values[key]; !ok {
+			return fmt.Errorf("error deleting key %s - %w", key, ErrParameterNotFound)
+		}
+		delete(values, key)
+		return nil
+	})
+}
+
+// Get returns a key of the object
+func (c *S3Configuration) Get(key string) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.load(context.Background()); err != nil {
+		return "", fmt.Errorf("error retrieving key %s - %w", key, err)
+	}
+
+	value, ok := c.values[key]
+
+	if !ok {
+		return "", fmt.Errorf("error retrieving key %s - %w", key, ErrParameterNotFound)
+	}
+
+	return value, nil
+}
+
+// GetEnvironment returns a copy of all the keys of the object
+func (c *S3Configuration) GetEnvironment() (map[string]string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.load(context.Background()); err != nil {
+		return nil, fmt.Errorf("error retrieving environment - %w", err)
+	}
+
+	values := make(map[string]string, len(c.values))
+	for k, v := range c.values {
+		values[k] = v
+	}
+
+	return values, nil
+}
+
+// Reload forces the object to be checked for changes on the next read
+func (c *S3Configuration) Reload() {
+	c.mu.Lock()
+	c.loadedAt = time.Time{}
+	c.mu.Unlock()
+}
+
+// update runs a read-modify-write cycle of the object, the caller must not hold mu
+func (c *S3Configuration) update(fn func(values map[string]string) error) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ctx := context.Background()
+	c.loadedAt = time.Time{}
+
+	if err := c.load(ctx); err != nil {
+		return fmt.Errorf("error reading object %s - %w", c.objectKey, err)
+	}
+
+	if !c.flat {
+		return fmt.Errorf("error writing object %s - %w", c.objectKey, ErrNotFlat)
+	}
+
+	values := make(map[string]string, len(c.values))
+	for k, v := range c.values {
+		values[k] = v
+	}
+
+	if err := fn(values); err != nil {
+		return err
+	}
+
+	body, err := c.encode(values)
+
+	if err != nil {
+		return fmt.Errorf("error encoding object %s - %w", c.objectKey, err)
+	}
+
+	// the object must still be the one read, or still be missing
+	condition := map[string]string{"If-None-Match": "*"}
+	if c.etag != "" {
+		condition = map[string]string{"If-Match": c.etag}
+	}
+
+	out, err := c.client.PutObjectWithContext(ctx, &s3.PutObjectInput{
+		Bucket: aws.String(c.bucket),
+		Key:    aws.String(c.objectKey),
+		Body:   bytes.NewReader(body),
+	}, request.WithSetRequestHeaders(condition))
+
+	var aerr awserr.Error
+	if errors.As(err, &aerr) && (aerr.Code() == "PreconditionFailed" || aerr.Code() == "ConditionalRequestConflict") {
+		c.values = nil
+		return fmt.Errorf("error writing object %s, it was changed since it was read - %w", c.objectKey, ErrVersionConflict)
+	}
+
+	if err != nil {
+		return fmt.Errorf("error writing object %s - %w", c.objectKey, err)
+	}
+
+	c.values = values
+	c.etag = aws.StringValue(out.ETag)
+	c.loadedAt = c.now()
+
+	return nil
+}
+
+// load (re)reads the object when the reload interval passed, the caller must hold mu
+// A missing object is treated as an empty one
+func (c *S3Configuration) load(ctx context.Context) error {
+	if c.values != nil && c.now().Sub(c.loadedAt) < c.reloadInterval {
+		return nil
+	}
+
+	input := &s3.GetObjectInput{
+		Bucket: aws.String(c.bucket),
+		Key:    aws.String(c.objectKey),
+	}
+
+	if c.values != nil && c.etag != "" {
+		input.IfNoneMatch = aws.String(c.etag)
+	}
+
+	out, err := c.client.GetObjectWithContext(ctx, input)
+
+	var aerr awserr.Error
+	if errors.As(err, &aerr) {
+		switch aerr.Code() {
+		case "NotModified":
+			c.loadedAt = c.now()
+			return nil
+		case s3.ErrCodeNoSuchKey:
+			c.values = make(map[string]string)
+			c.etag = ""
+			c.flat = true
+			c.loadedAt = c.now()
+			return nil
+		}
+	}
+
+	if err != nil {
+		return err
+	}
+	defer out.Body.Close()
+
+	body, err := ioutil.ReadAll(out.Body)
+
+	if err != nil {
+		return err
+	}
+
+	values, flat, err := c.decode(body)
+
+	if err != nil {
+		return fmt.Errorf("error parsing object %s - %w", c.objectKey, err)
+	}
+
+	c.values = values
+	c.flat = flat
+	c.etag = aws.StringValue(out.ETag)
+	c.loadedAt = c.now()
+
+	return nil
+}
+
+// decode parses YAML (and so JSON) into a flat map, also telling whether the document is flat already (see isFlat)
+func (c *S3Configuration) decode(body []byte) (map[string]string, bool, error) {
+	var node yaml.Node
+
+	if err := yaml.Unmarshal(body, &node); err != nil {
+		return nil, false, err
+	}
+
+	var doc map[string]interface{}
+
+	if node.Kind != 0 {
+		if err := node.Decode(&doc); err != nil {
+			return nil, false, err
+		}
+	}
+
+	values := make(map[string]string)
+	flatten("", doc, c.keyDelimitor, values)
+
+	return values, isFlat(&node), nil
+}
+
+// isFlat reports whether the document only holds top level strings without comments or anchors
+func isFlat(doc *yaml.Node) bool {
+	if doc.Kind == 0 {
+		return true
+	}
+
+	if hasDecoration(doc) || len(doc.Content) != 1 {
+		return false
+	}
+
+	root := doc.Content[0]
+
+	if root.Kind == yaml.ScalarNode && root.ShortTag() == "!!null" {
+		return !hasDecoration(root)
+	}
+
+	if root.Kind != yaml.MappingNode || hasDecoration(root) {
+		return false
+	}
+
+	for _, node := range root.Content {
+		if node.Kind != yaml.ScalarNode || node.ShortTag() != "!!str" || hasDecoration(node) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// hasDecoration reports whether the node has comments or an anchor, which encode does not write back
+func hasDecoration(node *yaml.Node) bool {
+	return node.HeadComment != "" || node.LineComment != "" || node.FootComment != "" || node.Anchor != ""
+}
+
+func (c *S3Configuration) encode(values map[string]string) ([]byte, error) {
+	if strings.HasSuffix(strings.ToLower(c.objectKey), ".json") {
+		return json.MarshalIndent(values, "", "  ")
+	}
+
+	return yaml.Marshal(values)
+}
+
+// flatten adds the scalars of doc to values, joining nested keys with the delimiter
+// Lists are joined with commas the same way as StringList parameters
+func flatten(prefix string, doc map[string]interface{}, delimiter string, values map[string]string) {
+	for k, v := range doc {
+		key := k
+		if prefix != "" {
+			key = prefix + delimiter + k
+		}
+
+		switch typed := v.(type) {
+		case map[string]interface{}:
+			flatten(key, typed, delimiter, values)
+		case []interface{}:
+			items := make([]string, len(typed))
+			for i, item := range typed {
+				items[i] = fmt.Sprint(item)
+			}
+			values[key] = strings.Join(items, ",")
+		case nil:
+			values[key] = ""
+		default:
+			values[key] = fmt.Sprint(typed)
+		}
+	}
+}
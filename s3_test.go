@@ -0,0 +1,205 @@
+package goawshelpers
+
+import (
+	"bytes"
+	"crypto/md5"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeS3 is an in-memory S3Client used by the tests
+type fakeS3 struct {
+	s3iface.S3API
+
+	mu      sync.Mutex
+	objects map[string][]byte
+	gets    int
+	// beforePut runs with mu held at the start of every put
+	beforePut func()
+}
+
+func newFakeS3() *fakeS3 {
+	return &fakeS3{objects: make(map[string][]byte)}
+}
+
+func (f *fakeS3) etag(key string) string {
+	return fmt.Sprintf("%x", md5.Sum(f.objects[key]))
+}
+
+func (f *fakeS3) GetObjectWithContext(ctx aws.Context, input *s3.GetObjectInput, opts ...request.Option) (*s3.GetObjectOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.gets++
+	body, ok := f.objects[*input.Key]
+	if !ok {
+		return nil, awserr.New(s3.ErrCodeNoSuchKey, "the specified key does not exist", nil)
+	}
+
+	if aws.StringValue(input.IfNoneMatch) == f.etag(*input.Key) {
+		return nil, awserr.New("NotModified", "not modified", nil)
+	}
+
+	return &s3.GetObjectOutput{
+		Body: ioutil.NopCloser(bytes.NewReader(body)),
+		ETag: aws.String(f.etag(*input.Key)),
+	}, nil
+}
+
+func (f *fakeS3) PutObjectWithContext(ctx aws.Context, input *s3.PutObjectInput, opts ...request.Option) (*s3.PutObjectOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.beforePut != nil {
+		f.beforePut()
+	}
+
+	req := &request.Request{HTTPRequest: &http.Request{Header: http.Header{}}}
+	for _, opt := range opts {
+		opt(req)
+	}
+
+	_, exists := f.objects[*input.Key]
+	match, noneMatch := req.HTTPRequest.Header.Get("If-Match"), req.HTTPRequest.Header.Get("If-None-Match")
+	if (match != "" && (!exists || match != f.etag(*input.Key))) || (noneMatch == "*" && exists) {
+		return nil, awserr.New("PreconditionFailed", "at least one of the pre-conditions you specified did not hold", nil)
+	}
+
+	body, err := ioutil.ReadAll(input.Body)
+	if err != nil {
+		return nil, err
+	}
+	f.objects[*input.Key] = body
+
+	return &s3.PutObjectOutput{ETag: aws.String(f.etag(*input.Key))}, nil
+}
+
+func Test_S3Configuration(t *testing.T) {
+	client := newFakeS3()
+	client.objects["dev.yaml"] = []byte("db:\n  host: localhost\n  port: 5432\nhosts: [a, b]\ndebug: true\n")
+
+	c := NewS3ConfigurationWithClient(client, S3ConfigurationInit{Bucket: "config", Key: "dev.yaml"})
+
+	val, err := c.Get("db_host")
+	assert.Nil(t, err)
+	assert.Equal(t, "localhost", val)
+
+	values, err := c.GetEnvironment()
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"db_host": "localhost", "db_port": "5432", "hosts": "a,b", "debug": "true"}, values)
+
+	_, err = c.Get("missing")
+	assert.True(t, errors.Is(err, ErrParameterNotFound))
+
+	// rewriting the object would flatten it
+	assert.True(t, errors.Is(c.Set("db_host", "db.internal"), ErrNotFlat))
+	assert.Equal(t, "db:\n  host: localhost\n  port: 5432\nhosts: [a, b]\ndebug: true\n", string(client.objects["dev.yaml"]))
+
+	client.objects["flat.yaml"] = []byte("db_host: localhost\ndebug: \"true\"\n")
+	flat := NewS3ConfigurationWithClient(client, S3ConfigurationInit{Bucket: "config", Key: "flat.yaml"})
+
+	assert.True(t, errors.Is(flat.Create("db_host", "other"), ErrParameterAlreadyExists))
+	assert.Nil(t, flat.Set("db_host", "db.internal"))
+	assert.Nil(t, flat.Delete("debug"))
+	assert.True(t, errors.Is(flat.Delete("debug"), ErrParameterNotFound))
+
+	reread := NewS3ConfigurationWithClient(client, S3ConfigurationInit{Bucket: "config", Key: "flat.yaml"})
+	values, err = reread.GetEnvironment()
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"db_host": "db.internal"}, values)
+}
+
+func Test_S3Configuration_NotFlat(t *testing.T) {
+	for _, body := range []string{
+		"port: 5432\n",
+		"key: value # the comment\n",
+		"# the comment\nkey: value\n",
+		"hosts: [a, b]\n",
+		"key: &anchor value\n",
+		`{"key": "value", "port": 5432}`,
+	} {
+		client := newFakeS3()
+		client.objects["dev.yaml"] = []byte(body)
+		c := NewS3ConfigurationWithClient(client, S3ConfigurationInit{Bucket: "config", Key: "dev.yaml"})
+
+		assert.True(t, errors.Is(c.Set("other", "value"), ErrNotFlat), body)
+		assert.Equal(t, body, string(client.objects["dev.yaml"]))
+	}
+}
+
+func Test_S3Configuration_Conflict(t *testing.T) {
+	client := newFakeS3()
+	client.objects["dev.yaml"] = []byte("key: v1\n")
+	c := NewS3ConfigurationWithClient(client, S3ConfigurationInit{Bucket: "config", Key: "dev.yaml"})
+
+	// a write between the read and the put of c
+	client.beforePut = func() {
+		client.objects["dev.yaml"] = []byte("key: v2\n")
+		client.objects["new.yaml"] = []byte("key: other\n")
+	}
+	assert.True(t, errors.Is(c.Set("other", "value"), ErrVersionConflict))
+	assert.Equal(t, "key: v2\n", string(client.objects["dev.yaml"]))
+
+	created := NewS3ConfigurationWithClient(client, S3ConfigurationInit{Bucket: "config", Key: "new.yaml"})
+	delete(client.objects, "new.yaml")
+	assert.True(t, errors.Is(created.Set("key", "value"), ErrVersionConflict))
+	assert.Equal(t, "key: other\n", string(client.objects["new.yaml"]))
+
+	client.beforePut = nil
+	assert.Nil(t, c.Set("other", "value"))
+	assert.Equal(t, "key: v2\nother: value\n", string(client.objects["dev.yaml"]))
+}
+
+func Test_S3Configuration_Reload(t *testing.T) {
+	now := time.Now()
+	client := newFakeS3()
+	client.objects["dev.json"] = []byte(`{"key": "v1"}`)
+
+	c := NewS3ConfigurationWithClient(client, S3ConfigurationInit{Bucket: "config", Key: "dev.json", ReloadInterval: time.Minute})
+	c.now = func() time.Time { return now }
+
+	val, _ := c.Get("key")
+	assert.Equal(t, "v1", val)
+
+	client.objects["dev.json"] = []byte(`{"key": "v2"}`)
+	val, _ = c.Get("key")
+	assert.Equal(t, "v1", val)
+	assert.Equal(t, 1, client.gets)
+
+	now = now.Add(time.Minute)
+	val, _ = c.Get("key")
+	assert.Equal(t, "v2", val)
+
+	now = now.Add(time.Minute)
+	val, _ = c.Get("key")
+	assert.Equal(t, "v2", val)
+	assert.Equal(t, 3, client.gets)
+
+	c.Reload()
+	assert.Nil(t, c.Set("other", "value"))
+	assert.JSONEq(t, `{"key": "v2", "other": "value"}`, string(client.objects["dev.json"]))
+}
+
+func Test_S3Configuration_MissingObject(t *testing.T) {
+	client := newFakeS3()
+	c := NewS3ConfigurationWithClient(client, S3ConfigurationInit{Bucket: "config", Key: "new.yaml"})
+
+	values, err := c.GetEnvironment()
+	assert.Nil(t, err)
+	assert.Empty(t, values)
+
+	assert.Nil(t, c.Create("key", "value"))
+	assert.Equal(t, "key: value\n", string(client.objects["new.yaml"]))
+}
@@ -0,0 +1,350 @@
+package goawshelpers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/secretsmanager"
+)
+
+var _ Configuration = (*SecretsManagerConfiguration)(nil)
+
+// SecretsManagerClient is the subset of the AWS Secrets Manager API used by SecretsManagerConfiguration
+// *secretsmanager.SecretsManager follows this interface, a mock can be used for testing
+type SecretsManagerClient interface {
+	GetSecretValueWithContext(ctx aws.Context, input *secretsmanager.GetSecretValueInput, opts ...request.Option) (*secretsmanager.GetSecretValueOutput, error)
+	PutSecretValueWithContext(ctx aws.Context, input *secretsmanager.PutSecretValueInput, opts ...request.Option) (*secretsmanager.PutSecretValueOutput, error)
+	CreateSecretWithContext(ctx aws.Context, input *secretsmanager.CreateSecretInput, opts ...request.Option) (*secretsmanager.CreateSecretOutput, error)
+	DeleteSecretWithContext(ctx aws.Context, input *secretsmanager.DeleteSecretInput, opts ...request.Option) (*secretsmanager.DeleteSecretOutput, error)
+	ListSecretsPagesWithContext(ctx aws.Context, input *secretsmanager.ListSecretsInput, fn func(*secretsmanager.ListSecretsOutput, bool) bool, opts ...request.Option) error
+}
+
+// SecretsManagerConfiguration provides an easy way to access secrets from AWS Secrets Manager
+// By default every key is a separate secret named like the SSM parameter path (/env/key/name)
+// When a SecretName is configured the keys of that single JSON secret are used as the environment instead
+// All methods are safe for concurrent use
+type SecretsManagerConfiguration struct {
+	client       SecretsManagerClient
+	env          string
+	keyDelimitor string
+	secretName   string
+	kmsKeyID     string
+	// mu serializes the read-modify-write cycles of the JSON secret
+	mu sync.Mutex
+}
+
+// SecretsManagerConfigurationInit helps to initialize SecretsManagerConfiguration
+type SecretsManagerConfigurationInit struct {
+	AWSInit
+	Env          string
+	KeyDelimitor string
+	// SecretName is a secret holding a JSON object whose keys are used as the environment
+	SecretName string
+	// KmsKeyId is the KMS key used to encrypt newly created secrets, the aws/secretsmanager key is used when empty
+	KmsKeyId string
+}
+
+// NewSecretsManagerConfiguration creates a new instance of SecretsManagerConfiguration based on the passed in parameters
+func NewSecretsManagerConfiguration(config SecretsManagerConfigurationInit) (*SecretsManagerConfiguration, error) {
+	sess, serviceConfig, err := newSession(config.AWSInit)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return NewSecretsManagerConfigurationWithClient(secretsmanager.New(sess, serviceConfig), config), nil
+}
+
+// NewSecretsManagerConfigurationWithClient creates a new instance of SecretsManagerConfiguration using an already built client
+// The AWSInit part of the config is ignored
+func NewSecretsManagerConfigurationWithClient(client SecretsManagerClient, config SecretsManagerConfigurationInit) *SecretsManagerConfiguration {
+	if config.KeyDelimitor == "" {
+		config.KeyDelimitor = defaultKeyDelimitor
+	}
+
+	return &SecretsManagerConfiguration{
+		client:       client,
+		env:          config.Env,
+		keyDelimitor: config.KeyDelimitor,
+		secretName:   config.SecretName,
+		kmsKeyID:     config.KmsKeyId,
+	}
+}
+
+// Create creates a new secret (or JSON key). If the key already exists - an error is returned
+func (c *SecretsManagerConfiguration) Create(key, value string) error {
+	ctx := context.Background()
+
+	if c.secretName != "" {
+		return c.updateJSON(ctx, func(values map[string]json.RawMessage) error {
+			if _, ok := values[key]; ok {
+				return fmt.Errorf("error creating a new entry with key %s - %w", key, ErrParameterAlreadyExists)
+			}
+			return setJSONValue(values, key, value)
+		})
+	}
+
+	if err := c.create(ctx, convertKeynameToPath(key, c.env, c.keyDelimitor), value); err != nil {
+		return fmt.Errorf("error creating a new entry - %w", err)
+	}
+	return nil
+}
+
+// Set creates or updates a secret (or JSON key)
+func (c *SecretsManagerConfiguration) Set(key, value string) error {
+	ctx := context.Background()
+
+	if c.secretName != "" {
+		return c.updateJSON(ctx, func(values map[string]json.RawMessage) error {
+			return setJSONValue(values, key, value)
+		})
+	}
+
+	if err := c.put(ctx, convertKeynameToPath(key, c.env, c.keyDelimitor), value); err != nil {
+		return fmt.Errorf("error setting an entry with key %s - %w", key, err)
+	}
+	return nil
+}
+
+// Delete deletes the secret (or JSON key)
+// Secrets are scheduled for deletion with the default recovery window, so the same name can not be created again right away
+func (c *SecretsManagerConfiguration) Delete(key string) error {
+	ctx := context.Background()
+
+	if c.secretName != "" {
+		return c.updateJSON(ctx, func(values map[string]json.RawMessage) error {
+			if _, ok := values[key]; !ok {
+				return fmt.Errorf("error deleting key %s - %w", key, ErrParameterNotFound)
+			}
+			delete(values, key)
+			return nil
+		})
+	}
+
+	_, err := c.client.DeleteSecretWithContext(ctx, &secretsmanager.DeleteSecretInput{
+		SecretId: aws.String(convertKeynameToPath(key, c.env, c.keyDelimitor)),
+	})
+
+	if err != nil {
+		return fmt.Errorf("error deleting key %s - %w", key, translateError(err))
+	}
+	return nil
+}
+
+// Get returns a secret (or JSON key) value
+func (c *SecretsManagerConfiguration) Get(key string) (string, error) {
+	ctx := context.Background()
+
+	if c.secretName != "" {
+		values, err := c.readJSON(ctx)
+
+		if err != nil {
+			return "", fmt.Errorf("error retrieving key %s - %w", key, err)
+		}
+
+		value, ok := values[key]
+
+		if !ok {
+			return "", fmt.Errorf("error retrieving key %s - %w", key, ErrParameterNotFound)
+		}
+
+		return value, nil
+	}
+
+	value, err := c.get(ctx, convertKeynameToPath(key, c.env, c.keyDelimitor))
+
+	if err != nil {
+		return "", fmt.Errorf("error retrieving key %s - %w", key, err)
+	}
+
+	return value, nil
+}
+
+// GetEnvironment returns all the secrets under the environment prefix (or all the keys of the JSON secret)
+func (c *SecretsManagerConfiguration) GetEnvironment() (map[string]string, error) {
+	ctx := context.Background()
+
+	if c.secretName != "" {
+		values, err := c.readJSON(ctx)
+
+		if errors.Is(err, ErrParameterNotFound) {
+			return make(map[string]string), nil
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("error retrieving environment - %w", err)
+		}
+
+		return values, nil
+	}
+
+	prefix := envPrefix(c.env)
+	var names []string
+
+	err := c.client.ListSecretsPagesWithContext(ctx, &secretsmanager.ListSecretsInput{
+		Filters: []*secretsmanager.Filter{{
+			Key:    aws.String(secretsmanager.FilterNameStringTypeName),
+			Values: aws.StringSlice([]string{prefix}),
+		}},
+	}, func(page *secretsmanager.ListSecretsOutput, lastPage bool) bool {
+		for _, secret := range page.SecretList {
+			// the name filter is case insensitive
+			if strings.HasPrefix(*secret.Name, prefix) {
+				names = append(names, *secret.Name)
+			}
+		}
+		return !lastPage
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("error listing secrets by environment - %w", err)
+	}
+
+	values := make(map[string]string, len(names))
+
+	for _, name := range names {
+		value, err := c.get(ctx, name)
+
+		if err != nil {
+			return nil, fmt.Errorf("error retrieving secret %s - %w", name, err)
+		}
+
+		values[convertPathToKeyname(name, c.env, c.keyDelimitor)] = value
+	}
+
+	return values, nil
+}
+
+func (c *SecretsManagerConfiguration) get(ctx context.Context, name string) (string, error) {
+	out, err := c.client.GetSecretValueWithContext(ctx, &secretsmanager.GetSecretValueInput{
+		SecretId: aws.String(name),
+	})
+
+	if err != nil {
+		return "", translateError(err)
+	}
+
+	if out.SecretString == nil {
+		return "", fmt.Errorf("secret %s has no string value", name)
+	}
+
+	return *out.SecretString, nil
+}
+
+func (c *SecretsManagerConfiguration) create(ctx context.Context, name, value string) error {
+	input := &secretsmanager.CreateSecretInput{
+		Name:         aws.String(name),
+		SecretString: aws.String(value),
+	}
+
+	if c.kmsKeyID != "" {
+		input.KmsKeyId = aws.String(c.kmsKeyID)
+	}
+
+	_, err := c.client.CreateSecretWithContext(ctx, input)
+
+	return translateError(err)
+}
+
+// put stores a new version of the secret, creating it if needed
+func (c *SecretsManagerConfiguration) put(ctx context.Context, name, value string) error {
+	_, err := c.client.PutSecretValueWithContext(ctx, &secretsmanager.PutSecretValueInput{
+		SecretId:     aws.String(name),
+		SecretString: aws.String(value),
+	})
+
+	if errors.Is(translateError(err), ErrParameterNotFound) {
+		return c.create(ctx, name, value)
+	}
+
+	return err
+}
+
+// readJSON returns the keys of the JSON secret, non string values are returned as raw JSON
+func (c *SecretsManagerConfiguration) readJSON(ctx context.Context) (map[string]string, error) {
+	raw, err := c.readRawJSON(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]string, len(raw))
+
+	for key, message := range raw {
+		var value string
+		if err := json.Unmarshal(message, &value); err != nil {
+			value = string(message)
+		}
+		values[key] = value
+	}
+
+	return values, nil
+}
+
+// readRawJSON returns the keys of the JSON secret with their values still encoded
+func (c *SecretsManagerConfiguration) readRawJSON(ctx context.Context) (map[string]json.RawMessage, error) {
+	secret, err := c.get(ctx, c.secretName)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var raw map[string]json.RawMessage
+
+	if err := json.Unmarshal([]byte(secret), &raw); err != nil {
+		return nil, fmt.Errorf("error decoding secret %s as a JSON object - %w", c.secretName, err)
+	}
+
+	return raw, nil
+}
+
+// setJSONValue stores value as a JSON string, the other keys keep their encoding (numbers, objects...)
+func setJSONValue(values map[string]json.RawMessage, key, value string) error {
+	encoded, err := json.Marshal(value)
+
+	if err != nil {
+		return fmt.Errorf("error encoding key %s - %w", key, err)
+	}
+	values[key] = encoded
+
+	return nil
+}
+
+// updateJSON applies fn to the keys of the JSON secret and stores the result
+// Only the keys changed by fn are encoded again
+func (c *SecretsManagerConfiguration) updateJSON(ctx context.Context, fn func(values map[string]json.RawMessage) error) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	values, err := c.readRawJSON(ctx)
+
+	if errors.Is(err, ErrParameterNotFound) {
+		values, err = make(map[string]json.RawMessage), nil
+	}
+
+	if err != nil {
+		return fmt.Errorf("error reading secret %s - %w", c.secretName, err)
+	}
+
+	if err := fn(values); err != nil {
+		return err
+	}
+
+	secret, err := json.Marshal(values)
+
+	if err != nil {
+		return fmt.Errorf("error encoding secret %s - %w", c.secretName, err)
+	}
+
+	if err := c.put(ctx, c.secretName, string(secret)); err != nil {
+		return fmt.Errorf("error updating secret %s - %w", c.secretName, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,151 @@
+package goawshelpers
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/secretsmanager"
+	"github.com/aws/aws-sdk-go/service/secretsmanager/secretsmanageriface"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeSecretsManager is an in-memory SecretsManagerClient used by the tests
+type fakeSecretsManager struct {
+	secretsmanageriface.SecretsManagerAPI
+
+	mu      sync.Mutex
+	secrets map[string]string
+}
+
+func newFakeSecretsManager() *fakeSecretsManager {
+	return &fakeSecretsManager{secrets: make(map[string]string)}
+}
+
+func (f *fakeSecretsManager) GetSecretValueWithContext(ctx aws.Context, input *secretsmanager.GetSecretValueInput, opts ...request.Option) (*secretsmanager.GetSecretValueOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	value, ok := f.secrets[*input.SecretId]
+	if !ok {
+		return nil, awserr.New(secretsmanager.ErrCodeResourceNotFoundException, "secret not found", nil)
+	}
+
+	return &secretsmanager.GetSecretValueOutput{Name: input.SecretId, SecretString: aws.String(value)}, nil
+}
+
+func (f *fakeSecretsManager) PutSecretValueWithContext(ctx aws.Context, input *secretsmanager.PutSecretValueInput, opts ...request.Option) (*secretsmanager.PutSecretValueOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, ok := f.secrets[*input.SecretId]; !ok {
+		return nil, awserr.New(secretsmanager.ErrCodeResourceNotFoundException, "secret not found", nil)
+	}
+	f.secrets[*input.SecretId] = *input.SecretString
+
+	return &secretsmanager.PutSecretValueOutput{}, nil
+}
+
+func (f *fakeSecretsManager) CreateSecretWithContext(ctx aws.Context, input *secretsmanager.CreateSecretInput, opts ...request.Option) (*secretsmanager.CreateSecretOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, ok := f.secrets[*input.Name]; ok {
+		return nil, awserr.New(secretsmanager.ErrCodeResourceExistsException, "secret already exists", nil)
+	}
+	f.secrets[*input.Name] = *input.SecretString
+
+	return &secretsmanager.CreateSecretOutput{Name: input.Name}, nil
+}
+
+func (f *fakeSecretsManager) DeleteSecretWithContext(ctx aws.Context, input *secretsmanager.DeleteSecretInput, opts ...request.Option) (*secretsmanager.DeleteSecretOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, ok := f.secrets[*input.SecretId]; !ok {
+		return nil, awserr.New(secretsmanager.ErrCodeResourceNotFoundException, "secret not found", nil)
+	}
+	delete(f.secrets, *input.SecretId)
+
+	return &secretsmanager.DeleteSecretOutput{}, nil
+}
+
+func (f *fakeSecretsManager) ListSecretsPagesWithContext(ctx aws.Context, input *secretsmanager.ListSecretsInput, fn func(*secretsmanager.ListSecretsOutput, bool) bool, opts ...request.Option) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	out := &secretsmanager.ListSecretsOutput{}
+	for name := range f.secrets {
+		if strings.HasPrefix(name, *input.Filters[0].Values[0]) {
+			out.SecretList = append(out.SecretList, &secretsmanager.SecretListEntry{Name: aws.String(name)})
+		}
+	}
+	fn(out, true)
+
+	return nil
+}
+
+func Test_SecretsManagerConfiguration(t *testing.T) {
+	client := newFakeSecretsManager()
+	c := NewSecretsManagerConfigurationWithClient(client, SecretsManagerConfigurationInit{Env: "dev"})
+
+	assert.Nil(t, c.Create("database_password", "secret"))
+	assert.True(t, errors.Is(c.Create("database_password", "other"), ErrParameterAlreadyExists))
+	assert.Equal(t, "secret", client.secrets["/dev/database/password"])
+
+	assert.Nil(t, c.Set("database_password", "rotated"))
+	assert.Nil(t, c.Set("api_key", "key"))
+
+	val, err := c.Get("database_password")
+	assert.Nil(t, err)
+	assert.Equal(t, "rotated", val)
+
+	_, err = c.Get("missing")
+	assert.True(t, errors.Is(err, ErrParameterNotFound))
+
+	client.secrets["/staging/api/key"] = "other"
+	values, err := c.GetEnvironment()
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"database_password": "rotated", "api_key": "key"}, values)
+
+	assert.Nil(t, c.Delete("api_key"))
+	assert.True(t, errors.Is(c.Delete("api_key"), ErrParameterNotFound))
+}
+
+func Test_SecretsManagerConfiguration_JSON(t *testing.T) {
+	client := newFakeSecretsManager()
+	client.secrets["dev/app"] = `{"DB_HOST":"localhost","DB_PORT":5432,"POOL":{"size":10}}`
+	c := NewSecretsManagerConfigurationWithClient(client, SecretsManagerConfigurationInit{SecretName: "dev/app"})
+
+	val, err := c.Get("DB_PORT")
+	assert.Nil(t, err)
+	assert.Equal(t, "5432", val)
+
+	_, err = c.Get("DB_USER")
+	assert.True(t, errors.Is(err, ErrParameterNotFound))
+
+	assert.Nil(t, c.Create("DB_USER", "admin"))
+	assert.True(t, errors.Is(c.Create("DB_USER", "root"), ErrParameterAlreadyExists))
+	assert.Nil(t, c.Set("DB_HOST", "db.internal"))
+	// the untouched keys keep their types
+	assert.JSONEq(t, `{"DB_HOST":"db.internal","DB_PORT":5432,"DB_USER":"admin","POOL":{"size":10}}`, client.secrets["dev/app"])
+
+	assert.Nil(t, c.Delete("DB_PORT"))
+	assert.True(t, errors.Is(c.Delete("DB_PORT"), ErrParameterNotFound))
+
+	values, err := c.GetEnvironment()
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"DB_HOST": "db.internal", "DB_USER": "admin", "POOL": `{"size":10}`}, values)
+
+	empty := NewSecretsManagerConfigurationWithClient(client, SecretsManagerConfigurationInit{SecretName: "dev/new"})
+	values, err = empty.GetEnvironment()
+	assert.Nil(t, err)
+	assert.Empty(t, values)
+
+	assert.Nil(t, empty.Set("KEY", "value"))
+	assert.Equal(t, `{"KEY":"value"}`, client.secrets["dev/new"])
+}
@@ -0,0 +1,153 @@
+package goawshelpers
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/client"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
+	"github.com/aws/aws-sdk-go/aws/endpoints"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+)
+
+// defaultRegion is used when no region is configured nor set in the environment, it is guarded by defaultRegionMu
+var (
+	defaultRegion   = "eu-north-1"
+	defaultRegionMu sync.RWMutex
+)
+
+// AWSInit holds the session settings shared by the AWS backed configurations
+// The fields behave the same as the ones on SSMConfigurationInit
+type AWSInit struct {
+	AwsAccessKey       string
+	AwsSecretAccessKey string
+	UseEnvParams       bool
+	Region             string
+	UseDefaultChain    bool
+	MaxRetries         int
+	RetryBaseDelay     time.Duration
+	AssumeRoleArn      string
+	ExternalId         string
+	RoleSessionName    string
+	Endpoint           string
+	DisableSSL         bool
+	StrictRegion       bool
+	UseFIPS            bool
+	// Logger gets a region Event when the package default region is used
+	Logger Logger
+}
+
+// newSession creates the aws session along with the config service clients should be created with
+func newSession(config AWSInit) (*session.Session, *aws.Config, error) {
+	var creds *credentials.Credentials
+	region := configuredRegion(config.Region)
+
+	if region == "" {
+		if config.StrictRegion {
+			return nil, nil, fmt.Errorf("no region configured, set Region, AWS_REGION or AWS_DEFAULT_REGION")
+		}
+
+		defaultRegionMu.RLock()
+		region = defaultRegion
+		defaultRegionMu.RUnlock()
+		if config.Logger != nil {
+			config.Logger.Log(Event{Op: "region", Key: region})
+		}
+	}
+
+	switch {
+	case config.UseDefaultChain:
+		// nil credentials make the session resolve the default chain
+	case config.UseEnvParams:
+		// AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN first, then the AWS_PROFILE of the shared credentials file
+		creds = credentials.NewChainCredentials([]credentials.Provider{
+			&credentials.EnvProvider{},
+			&credentials.SharedCredentialsProvider{},
+		})
+	default:
+		if config.AwsAccessKey == "" && config.AwsSecretAccessKey == "" {
+			return nil, nil, fmt.Errorf("no awsAccessKey and/or awsSecretAccessKey provided")
+		}
+
+		creds = credentials.NewStaticCredentials(config.AwsAccessKey, config.AwsSecretAccessKey, "")
+	}
+
+	// every session gets its own transport, so closing its idle connections doesn't affect other clients
+	awsConfig := &aws.Config{
+		Region:      aws.String(region),
+		Credentials: creds,
+		HTTPClient:  &http.Client{Transport: http.DefaultTransport.(*http.Transport).Clone()},
+	}
+
+	if config.Endpoint != "" {
+		awsConfig.Endpoint = aws.String(config.Endpoint)
+		awsConfig.S3ForcePathStyle = aws.Bool(true)
+	}
+
+	if config.DisableSSL {
+		awsConfig.DisableSSL = aws.Bool(true)
+	}
+
+	if config.UseFIPS {
+		awsConfig.UseFIPSEndpoint = endpoints.FIPSEndpointStateEnabled
+	}
+
+	if config.MaxRetries > 0 {
+		awsConfig = request.WithRetryer(awsConfig, client.DefaultRetryer{
+			NumMaxRetries:    config.MaxRetries,
+			MinRetryDelay:    config.RetryBaseDelay,
+			MinThrottleDelay: config.RetryBaseDelay,
+		})
+	}
+
+	sess, err := session.NewSession(awsConfig)
+
+	if err != nil {
+		return nil, nil, fmt.Errorf("error initializing aws session - %w", err)
+	}
+
+	serviceConfig := aws.NewConfig().WithRegion(region)
+
+	if config.AssumeRoleArn != "" {
+		serviceConfig = serviceConfig.WithCredentials(stscreds.NewCredentials(sess, config.AssumeRoleArn, func(p *stscreds.AssumeRoleProvider) {
+			if config.ExternalId != "" {
+				p.ExternalID = aws.String(config.ExternalId)
+			}
+			if config.RoleSessionName != "" {
+				p.RoleSessionName = config.RoleSessionName
+			}
+		}))
+	}
+
+	return sess, serviceConfig, nil
+}
+
+// SetDefaultRegion changes the region used when neither Region nor the AWS_REGION/AWS_DEFAULT_REGION env is set
+// It is safe to call while configurations are being built, only the ones built afterwards use the new region
+func SetDefaultRegion(region string) {
+	defaultRegionMu.Lock()
+	defaultRegion = region
+	defaultRegionMu.Unlock()
+}
+
+// configuredRegion returns the region if set, otherwise the one from AWS_REGION or AWS_DEFAULT_REGION
+// An empty string is returned when no region is set
+func configuredRegion(region string) string {
+	if region != "" {
+		return region
+	}
+
+	for _, name := range []string{"AWS_REGION", "AWS_DEFAULT_REGION"} {
+		if region := os.Getenv(name); region != "" {
+			return region
+		}
+	}
+
+	return ""
+}
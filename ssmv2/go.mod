@@ -0,0 +1,12 @@
+module github.com/meilirobots/goawshelpers/ssmv2
+
+go 1.14
+
+require (
+	github.com/aws/aws-sdk-go v1.44.0
+	github.com/aws/aws-sdk-go-v2 v1.16.2
+	github.com/aws/aws-sdk-go-v2/service/ssm v1.24.1
+	github.com/aws/smithy-go v1.11.2
+	github.com/meilirobots/goawshelpers v0.0.0-20261014112113-48033b7a1a67
+	github.com/stretchr/testify v1.7.0
+)
@@ -0,0 +1,409 @@
+// Package ssmv2 runs goawshelpers.SSMConfiguration on top of aws-sdk-go-v2
+// It only replaces the transport: the v2 client does the calls (credentials, signing, retries, endpoints),
+// but goawshelpers is built on the aws-sdk-go v1 types, so v1 stays a required dependency
+// It is a separate module, so users of goawshelpers alone don't depend on aws-sdk-go-v2
+package ssmv2
+
+import (
+	"context"
+	"errors"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"github.com/aws/aws-sdk-go-v2/service/ssm/types"
+	awsv1 "github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	ssmv1 "github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/aws/smithy-go"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+
+	"github.com/meilirobots/goawshelpers"
+)
+
+// API is the subset of the aws-sdk-go-v2 SSM API used by Client
+// *ssm.Client follows this interface, a mock can be used for testing
+type API interface {
+	GetParameter(ctx context.Context, params *ssm.GetParameterInput, optFns ...func(*ssm.Options)) (*ssm.GetParameterOutput, error)
+	GetParameters(ctx context.Context, params *ssm.GetParametersInput, optFns ...func(*ssm.Options)) (*ssm.GetParametersOutput, error)
+	PutParameter(ctx context.Context, params *ssm.PutParameterInput, optFns ...func(*ssm.Options)) (*ssm.PutParameterOutput, error)
+	AddTagsToResource(ctx context.Context, params *ssm.AddTagsToResourceInput, optFns ...func(*ssm.Options)) (*ssm.AddTagsToResourceOutput, error)
+	DeleteParameter(ctx context.Context, params *ssm.DeleteParameterInput, optFns ...func(*ssm.Options)) (*ssm.DeleteParameterOutput, error)
+	DeleteParameters(ctx context.Context, params *ssm.DeleteParametersInput, optFns ...func(*ssm.Options)) (*ssm.DeleteParametersOutput, error)
+	GetParametersByPath(ctx context.Context, params *ssm.GetParametersByPathInput, optFns ...func(*ssm.Options)) (*ssm.GetParametersByPathOutput, error)
+	GetParameterHistory(ctx context.Context, params *ssm.GetParameterHistoryInput, optFns ...func(*ssm.Options)) (*ssm.GetParameterHistoryOutput, error)
+	DescribeParameters(ctx context.Context, params *ssm.DescribeParametersInput, optFns ...func(*ssm.Options)) (*ssm.DescribeParametersOutput, error)
+	LabelParameterVersion(ctx context.Context, params *ssm.LabelParameterVersionInput, optFns ...func(*ssm.Options)) (*ssm.LabelParameterVersionOutput, error)
+	UnlabelParameterVersion(ctx context.Context, params *ssm.UnlabelParameterVersionInput, optFns ...func(*ssm.Options)) (*ssm.UnlabelParameterVersionOutput, error)
+}
+
+var _ API = (*ssm.Client)(nil)
+
+// Client is a goawshelpers.SSMClient calling SSM through aws-sdk-go-v2
+// Errors are returned as awserr.Error with the v2 error code, so ErrParameterNotFound, Ping etc. behave the same as with v1
+// The v1 request options are ignored, the v2 client options apply instead
+type Client struct {
+	api API
+}
+
+var _ goawshelpers.SSMClient = (*Client)(nil)
+
+// NewClient returns a new instance of Client calling api
+func NewClient(api API) *Client {
+	return &Client{api: api}
+}
+
+// NewSSMConfiguration creates a new instance of goawshelpers.SSMConfiguration calling SSM with the v2 client built from cfg
+// Everything but the AWS session settings of config is applied (see goawshelpers.NewSSMConfigurationFromClient),
+// credentials, region, retries, endpoint and FIPS come from cfg and optFns instead
+func NewSSMConfiguration(cfg aws.Config, config goawshelpers.SSMConfigurationInit, optFns ...func(*ssm.Options)) (*goawshelpers.SSMConfiguration, error) {
+	return goawshelpers.NewSSMConfigurationFromClient(NewClient(ssm.NewFromConfig(cfg, optFns...)), config)
+}
+
+func (c *Client) GetParameterWithContext(ctx awsv1.Context, input *ssmv1.GetParameterInput, opts ...request.Option) (*ssmv1.GetParameterOutput, error) {
+	out, err := c.api.GetParameter(ctx, &ssm.GetParameterInput{
+		Name:           input.Name,
+		WithDecryption: awsv1.BoolValue(input.WithDecryption),
+	})
+
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	output := &ssmv1.GetParameterOutput{}
+	if out.Parameter != nil {
+		output.Parameter = parameter(*out.Parameter)
+	}
+
+	return output, nil
+}
+
+func (c *Client) GetParametersWithContext(ctx awsv1.Context, input *ssmv1.GetParametersInput, opts ...request.Option) (*ssmv1.GetParametersOutput, error) {
+	out, err := c.api.GetParameters(ctx, &ssm.GetParametersInput{
+		Names:          awsv1.StringValueSlice(input.Names),
+		WithDecryption: awsv1.BoolValue(input.WithDecryption),
+	})
+
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	return &ssmv1.GetParametersOutput{
+		Parameters:        parameters(out.Parameters),
+		InvalidParameters: awsv1.StringSlice(out.InvalidParameters),
+	}, nil
+}
+
+func (c *Client) PutParameterWithContext(ctx awsv1.Context, input *ssmv1.PutParameterInput, opts ...request.Option) (*ssmv1.PutParameterOutput, error) {
+	out, err := c.api.PutParameter(ctx, &ssm.PutParameterInput{
+		Name:           input.Name,
+		Value:          input.Value,
+		AllowedPattern: input.AllowedPattern,
+		DataType:       input.DataType,
+		Description:    input.Description,
+		KeyId:          input.KeyId,
+		Overwrite:      awsv1.BoolValue(input.Overwrite),
+		Policies:       input.Policies,
+		Tags:           tags(input.Tags),
+		Tier:           types.ParameterTier(awsv1.StringValue(input.Tier)),
+		Type:           types.ParameterType(awsv1.StringValue(input.Type)),
+	})
+
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	return &ssmv1.PutParameterOutput{
+		Tier:    enum(string(out.Tier)),
+		Version: awsv1.Int64(out.Version),
+	}, nil
+}
+
+func (c *Client) AddTagsToResourceWithContext(ctx awsv1.Context, input *ssmv1.AddTagsToResourceInput, opts ...request.Option) (*ssmv1.AddTagsToResourceOutput, error) {
+	_, err := c.api.AddTagsToResource(ctx, &ssm.AddTagsToResourceInput{
+		ResourceId:   input.ResourceId,
+		ResourceType: types.ResourceTypeForTagging(awsv1.StringValue(input.ResourceType)),
+		Tags:         tags(input.Tags),
+	})
+
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	return &ssmv1.AddTagsToResourceOutput{}, nil
+}
+
+func (c *Client) DeleteParameterWithContext(ctx awsv1.Context, input *ssmv1.DeleteParameterInput, opts ...request.Option) (*ssmv1.DeleteParameterOutput, error) {
+	_, err := c.api.DeleteParameter(ctx, &ssm.DeleteParameterInput{
+		Name: input.Name,
+	})
+
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	return &ssmv1.DeleteParameterOutput{}, nil
+}
+
+func (c *Client) DeleteParametersWithContext(ctx awsv1.Context, input *ssmv1.DeleteParametersInput, opts ...request.Option) (*ssmv1.DeleteParametersOutput, error) {
+	out, err := c.api.DeleteParameters(ctx, &ssm.DeleteParametersInput{
+		Names: awsv1.StringValueSlice(input.Names),
+	})
+
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	return &ssmv1.DeleteParametersOutput{
+		DeletedParameters: awsv1.StringSlice(out.DeletedParameters),
+		InvalidParameters: awsv1.StringSlice(out.InvalidParameters),
+	}, nil
+}
+
+func (c *Client) GetParametersByPathPagesWithContext(ctx awsv1.Context, input *ssmv1.GetParametersByPathInput, fn func(*ssmv1.GetParametersByPathOutput, bool) bool, opts ...request.Option) error {
+	paginator := ssm.NewGetParametersByPathPaginator(c.api, &ssm.GetParametersByPathInput{
+		Path:             input.Path,
+		MaxResults:       int32(awsv1.Int64Value(input.MaxResults)),
+		NextToken:        input.NextToken,
+		ParameterFilters: stringFilters(input.ParameterFilters),
+		Recursive:        awsv1.BoolValue(input.Recursive),
+		WithDecryption:   awsv1.BoolValue(input.WithDecryption),
+	})
+
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+
+		if err != nil {
+			return translateError(err)
+		}
+
+		if !fn(&ssmv1.GetParametersByPathOutput{Parameters: parameters(page.Parameters), NextToken: page.NextToken}, !paginator.HasMorePages()) {
+			return nil
+		}
+	}
+
+	return nil
+}
+
+func (c *Client) GetParameterHistoryPagesWithContext(ctx awsv1.Context, input *ssmv1.GetParameterHistoryInput, fn func(*ssmv1.GetParameterHistoryOutput, bool) bool, opts ...request.Option) error {
+	paginator := ssm.NewGetParameterHistoryPaginator(c.api, &ssm.GetParameterHistoryInput{
+		Name:           input.Name,
+		MaxResults:     int32(awsv1.Int64Value(input.MaxResults)),
+		NextToken:      input.NextToken,
+		WithDecryption: awsv1.BoolValue(input.WithDecryption),
+	})
+
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+
+		if err != nil {
+			return translateError(err)
+		}
+
+		history := make([]*ssmv1.ParameterHistory, 0, len(page.Parameters))
+		for _, param := range page.Parameters {
+			history = append(history, &ssmv1.ParameterHistory{
+				AllowedPattern:   param.AllowedPattern,
+				DataType:         param.DataType,
+				Description:      param.Description,
+				KeyId:            param.KeyId,
+				Labels:           awsv1.StringSlice(param.Labels),
+				LastModifiedDate: param.LastModifiedDate,
+				LastModifiedUser: param.LastModifiedUser,
+				Name:             param.Name,
+				Policies:         policies(param.Policies),
+				Tier:             enum(string(param.Tier)),
+				Type:             enum(string(param.Type)),
+				Value:            param.Value,
+				Version:          awsv1.Int64(param.Version),
+			})
+		}
+
+		if !fn(&ssmv1.GetParameterHistoryOutput{Parameters: history, NextToken: page.NextToken}, !paginator.HasMorePages()) {
+			return nil
+		}
+	}
+
+	return nil
+}
+
+func (c *Client) DescribeParametersPagesWithContext(ctx awsv1.Context, input *ssmv1.DescribeParametersInput, fn func(*ssmv1.DescribeParametersOutput, bool) bool, opts ...request.Option) error {
+	filters := make([]types.ParametersFilter, 0, len(input.Filters))
+	for _, filter := range input.Filters {
+		filters = append(filters, types.ParametersFilter{
+			Key:    types.ParametersFilterKey(awsv1.StringValue(filter.Key)),
+			Values: awsv1.StringValueSlice(filter.Values),
+		})
+	}
+
+	paginator := ssm.NewDescribeParametersPaginator(c.api, &ssm.DescribeParametersInput{
+		Filters:          filters,
+		MaxResults:       int32(awsv1.Int64Value(input.MaxResults)),
+		NextToken:        input.NextToken,
+		ParameterFilters: stringFilters(input.ParameterFilters),
+	})
+
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+
+		if err != nil {
+			return translateError(err)
+		}
+
+		metadata := make([]*ssmv1.ParameterMetadata, 0, len(page.Parameters))
+		for _, param := range page.Parameters {
+			metadata = append(metadata, &ssmv1.ParameterMetadata{
+				AllowedPattern:   param.AllowedPattern,
+				DataType:         param.DataType,
+				Description:      param.Description,
+				KeyId:            param.KeyId,
+				LastModifiedDate: param.LastModifiedDate,
+				LastModifiedUser: param.LastModifiedUser,
+				Name:             param.Name,
+				Policies:         policies(param.Policies),
+				Tier:             enum(string(param.Tier)),
+				Type:             enum(string(param.Type)),
+				Version:          awsv1.Int64(param.Version),
+			})
+		}
+
+		if !fn(&ssmv1.DescribeParametersOutput{Parameters: metadata, NextToken: page.NextToken}, !paginator.HasMorePages()) {
+			return nil
+		}
+	}
+
+	return nil
+}
+
+func (c *Client) LabelParameterVersionWithContext(ctx awsv1.Context, input *ssmv1.LabelParameterVersionInput, opts ...request.Option) (*ssmv1.LabelParameterVersionOutput, error) {
+	out, err := c.api.LabelParameterVersion(ctx, &ssm.LabelParameterVersionInput{
+		Name:             input.Name,
+		Labels:           awsv1.StringValueSlice(input.Labels),
+		ParameterVersion: awsv1.Int64Value(input.ParameterVersion),
+	})
+
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	return &ssmv1.LabelParameterVersionOutput{
+		InvalidLabels:    awsv1.StringSlice(out.InvalidLabels),
+		ParameterVersion: awsv1.Int64(out.ParameterVersion),
+	}, nil
+}
+
+func (c *Client) UnlabelParameterVersionWithContext(ctx awsv1.Context, input *ssmv1.UnlabelParameterVersionInput, opts ...request.Option) (*ssmv1.UnlabelParameterVersionOutput, error) {
+	out, err := c.api.UnlabelParameterVersion(ctx, &ssm.UnlabelParameterVersionInput{
+		Name:             input.Name,
+		Labels:           awsv1.StringValueSlice(input.Labels),
+		ParameterVersion: awsv1.Int64Value(input.ParameterVersion),
+	})
+
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	return &ssmv1.UnlabelParameterVersionOutput{
+		InvalidLabels: awsv1.StringSlice(out.InvalidLabels),
+		RemovedLabels: awsv1.StringSlice(out.RemovedLabels),
+	}, nil
+}
+
+// translateError turns a v2 error into the awserr.Error goawshelpers expects, context errors are kept as they are
+func translateError(err error) error {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		return awserr.New(apiErr.ErrorCode(), apiErr.ErrorMessage(), err)
+	}
+
+	var signErr *v4.SigningError
+	if errors.As(err, &signErr) {
+		return awserr.New("NoCredentialProviders", signErr.Error(), err)
+	}
+
+	var sendErr *smithyhttp.RequestSendError
+	if errors.As(err, &sendErr) {
+		return awserr.New(request.ErrCodeRequestError, "send request failed", err)
+	}
+
+	return err
+}
+
+func parameter(param types.Parameter) *ssmv1.Parameter {
+	return &ssmv1.Parameter{
+		ARN:              param.ARN,
+		DataType:         param.DataType,
+		LastModifiedDate: param.LastModifiedDate,
+		Name:             param.Name,
+		Selector:         param.Selector,
+		SourceResult:     param.SourceResult,
+		Type:             enum(string(param.Type)),
+		Value:            param.Value,
+		Version:          awsv1.Int64(param.Version),
+	}
+}
+
+func parameters(params []types.Parameter) []*ssmv1.Parameter {
+	converted := make([]*ssmv1.Parameter, 0, len(params))
+	for _, param := range params {
+		converted = append(converted, parameter(param))
+	}
+	return converted
+}
+
+func tags(tags []*ssmv1.Tag) []types.Tag {
+	if len(tags) == 0 {
+		return nil
+	}
+
+	converted := make([]types.Tag, 0, len(tags))
+	for _, tag := range tags {
+		converted = append(converted, types.Tag{Key: tag.Key, Value: tag.Value})
+	}
+	return converted
+}
+
+func stringFilters(filters []*ssmv1.ParameterStringFilter) []types.ParameterStringFilter {
+	if len(filters) == 0 {
+		return nil
+	}
+
+	converted := make([]types.ParameterStringFilter, 0, len(filters))
+	for _, filter := range filters {
+		converted = append(converted, types.ParameterStringFilter{
+			Key:    filter.Key,
+			Option: filter.Option,
+			Values: awsv1.StringValueSlice(filter.Values),
+		})
+	}
+	return converted
+}
+
+func policies(policies []types.ParameterInlinePolicy) []*ssmv1.ParameterInlinePolicy {
+	if len(policies) == 0 {
+		return nil
+	}
+
+	converted := make([]*ssmv1.ParameterInlinePolicy, 0, len(policies))
+	for _, policy := range policies {
+		converted = append(converted, &ssmv1.ParameterInlinePolicy{
+			PolicyStatus: policy.PolicyStatus,
+			PolicyText:   policy.PolicyText,
+			PolicyType:   policy.PolicyType,
+		})
+	}
+	return converted
+}
+
+// enum returns nil for the empty value v2 uses when an enum field is not set
+func enum(value string) *string {
+	if value == "" {
+		return nil
+	}
+	return awsv1.String(value)
+}
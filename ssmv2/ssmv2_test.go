@@ -0,0 +1,263 @@
+package ssmv2
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"github.com/aws/aws-sdk-go-v2/service/ssm/types"
+	"github.com/aws/smithy-go"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/meilirobots/goawshelpers"
+)
+
+// fakeAPI is an in-memory API used by the tests, lists return one parameter per page to exercise paging
+type fakeAPI struct {
+	API
+
+	mu      sync.Mutex
+	params  map[string]types.Parameter
+	history map[string][]types.ParameterHistory
+}
+
+func newFakeAPI() *fakeAPI {
+	return &fakeAPI{
+		params:  make(map[string]types.Parameter),
+		history: make(map[string][]types.ParameterHistory),
+	}
+}
+
+func notFound() error {
+	return &smithy.GenericAPIError{Code: "ParameterNotFound", Message: "parameter not found"}
+}
+
+func (f *fakeAPI) GetParameter(ctx context.Context, params *ssm.GetParameterInput, optFns ...func(*ssm.Options)) (*ssm.GetParameterOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	param, ok := f.params[*params.Name]
+	if !ok {
+		return nil, notFound()
+	}
+
+	return &ssm.GetParameterOutput{Parameter: &param}, nil
+}
+
+func (f *fakeAPI) PutParameter(ctx context.Context, params *ssm.PutParameterInput, optFns ...func(*ssm.Options)) (*ssm.PutParameterOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	version := int64(1)
+	if existing, ok := f.params[*params.Name]; ok {
+		if !params.Overwrite {
+			return nil, &smithy.GenericAPIError{Code: "ParameterAlreadyExists", Message: "parameter already exists"}
+		}
+		version = existing.Version + 1
+	}
+
+	f.params[*params.Name] = types.Parameter{
+		Name:             params.Name,
+		Value:            params.Value,
+		Type:             params.Type,
+		Version:          version,
+		LastModifiedDate: aws.Time(time.Now()),
+	}
+	f.history[*params.Name] = append(f.history[*params.Name], types.ParameterHistory{
+		Name:    params.Name,
+		Value:   params.Value,
+		Type:    params.Type,
+		Version: version,
+	})
+
+	return &ssm.PutParameterOutput{Version: version}, nil
+}
+
+func (f *fakeAPI) DeleteParameter(ctx context.Context, params *ssm.DeleteParameterInput, optFns ...func(*ssm.Options)) (*ssm.DeleteParameterOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, ok := f.params[*params.Name]; !ok {
+		return nil, notFound()
+	}
+	delete(f.params, *params.Name)
+	delete(f.history, *params.Name)
+
+	return &ssm.DeleteParameterOutput{}, nil
+}
+
+func (f *fakeAPI) GetParametersByPath(ctx context.Context, params *ssm.GetParametersByPathInput, optFns ...func(*ssm.Options)) (*ssm.GetParametersByPathOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var names []string
+	for name := range f.params {
+		if strings.HasPrefix(name, *params.Path) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	start, _ := strconv.Atoi(aws.ToString(params.NextToken))
+	if start >= len(names) {
+		return &ssm.GetParametersByPathOutput{}, nil
+	}
+
+	out := &ssm.GetParametersByPathOutput{Parameters: []types.Parameter{f.params[names[start]]}}
+	if start+1 < len(names) {
+		out.NextToken = aws.String(strconv.Itoa(start + 1))
+	}
+
+	return out, nil
+}
+
+func (f *fakeAPI) GetParameterHistory(ctx context.Context, params *ssm.GetParameterHistoryInput, optFns ...func(*ssm.Options)) (*ssm.GetParameterHistoryOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	history, ok := f.history[*params.Name]
+	if !ok {
+		return nil, notFound()
+	}
+
+	return &ssm.GetParameterHistoryOutput{Parameters: history}, nil
+}
+
+func (f *fakeAPI) LabelParameterVersion(ctx context.Context, params *ssm.LabelParameterVersionInput, optFns ...func(*ssm.Options)) (*ssm.LabelParameterVersionOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	history, ok := f.history[*params.Name]
+	if !ok {
+		return nil, notFound()
+	}
+
+	version := params.ParameterVersion
+	if version == 0 {
+		version = int64(len(history))
+	}
+	history[version-1].Labels = append(history[version-1].Labels, params.Labels...)
+
+	return &ssm.LabelParameterVersionOutput{ParameterVersion: version}, nil
+}
+
+func Test_Client(t *testing.T) {
+	api := newFakeAPI()
+	c, err := goawshelpers.NewSSMConfigurationFromClient(NewClient(api), goawshelpers.SSMConfigurationInit{Env: "dev", KeyDelimitor: "_"})
+	assert.Nil(t, err)
+
+	assert.Nil(t, c.Create("database_host", "db"))
+	assert.True(t, errors.Is(c.Create("database_host", "other"), goawshelpers.ErrParameterAlreadyExists))
+	assert.Nil(t, c.Set("database_port", "5432"))
+	assert.Nil(t, c.Set("debug", "true"))
+	assert.Equal(t, "db", aws.ToString(api.params["/dev/database/host"].Value))
+
+	val, err := c.Get("database_host")
+	assert.Nil(t, err)
+	assert.Equal(t, "db", val)
+
+	values, err := c.GetEnvironment()
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"database_host": "db", "database_port": "5432", "debug": "true"}, values)
+
+	param, err := c.GetWithMetadata("database_port")
+	assert.Nil(t, err)
+	assert.Equal(t, "String", param.Type)
+	assert.Equal(t, int64(1), param.Version)
+
+	assert.Nil(t, c.Set("debug", "false"))
+	assert.Nil(t, c.LabelVersion("debug", 1, []string{"production"}))
+	history, err := c.GetHistory("debug")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"production"}, history[1].Labels)
+
+	assert.Nil(t, c.Delete("debug"))
+	_, err = c.Get("debug")
+	assert.True(t, errors.Is(err, goawshelpers.ErrParameterNotFound))
+	assert.True(t, errors.Is(c.Delete("debug"), goawshelpers.ErrParameterNotFound))
+}
+
+func Test_NewSSMConfiguration(t *testing.T) {
+	var targets []string
+	var mu sync.Mutex
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		target := strings.TrimPrefix(r.Header.Get("X-Amz-Target"), "AmazonSSM.")
+
+		mu.Lock()
+		targets = append(targets, target)
+		mu.Unlock()
+
+		var input map[string]interface{}
+		_ = json.Unmarshal(body, &input)
+
+		w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+		switch {
+		case target == "GetParameter" && input["Name"] == "/dev/database/host":
+			fmt.Fprint(w, `{"Parameter":{"Name":"/dev/database/host","Type":"String","Value":"db","Version":3}}`)
+		case target == "GetParameter":
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"__type":"ParameterNotFound","message":"parameter not found"}`)
+		case target == "DescribeParameters" && r.Header.Get("Authorization") != "":
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"__type":"UnrecognizedClientException","message":"The security token included in the request is invalid."}`)
+		}
+	}))
+	defer server.Close()
+
+	cfg := aws.Config{
+		Region: "us-east-1",
+		Credentials: aws.CredentialsProviderFunc(func(ctx context.Context) (aws.Credentials, error) {
+			return aws.Credentials{AccessKeyID: "key", SecretAccessKey: "secret"}, nil
+		}),
+		Retryer: func() aws.Retryer { return aws.NopRetryer{} },
+	}
+	endpoint := func(o *ssm.Options) { o.EndpointResolver = ssm.EndpointResolverFromURL(server.URL) }
+
+	c, err := NewSSMConfiguration(cfg, goawshelpers.SSMConfigurationInit{Env: "dev", Decrypt: true}, endpoint)
+	assert.Nil(t, err)
+
+	val, err := c.Get("DATABASE_HOST")
+	assert.Nil(t, err)
+	assert.Equal(t, "db", val)
+
+	_, err = c.Get("missing")
+	assert.True(t, errors.Is(err, goawshelpers.ErrParameterNotFound))
+
+	assert.True(t, errors.Is(c.Ping(context.Background()), goawshelpers.ErrAccessDenied))
+	assert.Equal(t, []string{"GetParameter", "GetParameter", "DescribeParameters"}, targets)
+
+	cfg.Credentials = aws.CredentialsProviderFunc(func(ctx context.Context) (aws.Credentials, error) {
+		return aws.Credentials{}, errors.New("no credentials")
+	})
+	c, err = NewSSMConfiguration(cfg, goawshelpers.SSMConfigurationInit{Env: "dev"}, endpoint)
+	assert.Nil(t, err)
+	assert.True(t, errors.Is(c.Ping(context.Background()), goawshelpers.ErrAccessDenied))
+
+	_, err = NewSSMConfiguration(cfg, goawshelpers.SSMConfigurationInit{Env: "dev", Tier: "Premium"})
+	assert.NotNil(t, err)
+}
+
+func Test_translateError(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := translateError(fmt.Errorf("operation error SSM: GetParameter - %w", ctx.Err()))
+	assert.True(t, errors.Is(err, context.Canceled))
+
+	other := errors.New("other")
+	assert.Equal(t, other, translateError(other))
+}
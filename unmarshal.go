@@ -0,0 +1,203 @@
+package goawshelpers
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// Unmarshal populates the struct pointed to by out with the values from the configuration environment
+// Fields are matched using the `ssm:"key"` tag, fields without the tag are skipped
+// A `default:"value"` tag is used when the key is missing, otherwise the field is required
+// The default can also be given inside the tag, e.g. `ssm:"timeout,default=30s"`, as the last option it takes the rest of the tag (commas included)
+// Supported field kinds are string, bool, ints, floats and time.Duration
+func Unmarshal(c Configuration, out interface{}) error {
+	rv := reflect.ValueOf(out)
+
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("error unmarshalling configuration - out must be a non nil pointer to a struct")
+	}
+
+	values, err := c.GetEnvironment()
+
+	if err != nil {
+		return fmt.Errorf("error unmarshalling configuration - %w", err)
+	}
+
+	rv = rv.Elem()
+	rt := rv.Type()
+
+	var missing []string
+
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		tag, ok := parseTag(field)
+
+		if !ok {
+			continue
+		}
+
+		key := tag.key
+		raw, ok := values[key]
+
+		if !ok {
+			if raw, ok = tag.def, tag.hasDefault; !ok {
+				missing = append(missing, key)
+				continue
+			}
+		}
+
+		if err := setField(rv.Field(i), raw); err != nil {
+			return fmt.Errorf("error unmarshalling key %s into field %s - %w", key, field.Name, err)
+		}
+	}
+
+	if len(missing) > 0 {
+		return fmt.Errorf("error unmarshalling configuration - missing required keys: %s", strings.Join(missing, ", "))
+	}
+
+	return nil
+}
+
+// BindConfig is the same as Unmarshal but only fields tagged as required, e.g. `ssm:"db_url,required"`, must exist
+// Other missing fields keep their default (`ssm:"timeout,default=30s"`) or are left untouched
+// Every missing required key and every parse failure is reported at once as KeyErrors, so all of them can be fixed in one go
+func BindConfig(c Configuration, out interface{}) error {
+	rv := reflect.ValueOf(out)
+
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("error binding configuration - out must be a non nil pointer to a struct")
+	}
+
+	values, err := c.GetEnvironment()
+
+	if err != nil {
+		return fmt.Errorf("error binding configuration - %w", err)
+	}
+
+	rv = rv.Elem()
+	rt := rv.Type()
+
+	errs := make(KeyErrors)
+
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		tag, ok := parseTag(field)
+
+		if !ok {
+			continue
+		}
+
+		raw, ok := values[tag.key]
+
+		if !ok {
+			if tag.required {
+				errs[tag.key] = fmt.Errorf("required by field %s - %w", field.Name, ErrParameterNotFound)
+				continue
+			}
+			if !tag.hasDefault {
+				continue
+			}
+			raw = tag.def
+		}
+
+		if err := setField(rv.Field(i), raw); err != nil {
+			errs[tag.key] = fmt.Errorf("error parsing into field %s - %w", field.Name, err)
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("error binding configuration - %w", errs)
+	}
+
+	return nil
+}
+
+// fieldTag is a parsed `ssm:"key,required,default=value"` struct tag
+type fieldTag struct {
+	key        string
+	required   bool
+	def        string
+	hasDefault bool
+}
+
+// parseTag returns the ssm tag of field, ok is false when the field is not bound
+// A separate `default:"value"` tag is still accepted, the inline default takes precedence
+func parseTag(field reflect.StructField) (tag fieldTag, ok bool) {
+	value, ok := field.Tag.Lookup("ssm")
+
+	if !ok || value == "" || value == "-" {
+		return tag, false
+	}
+
+	tag.def, tag.hasDefault = field.Tag.Lookup("default")
+
+	// default= is the last option, so its value can hold commas
+	if i := strings.Index(value, ",default="); i >= 0 {
+		tag.def, tag.hasDefault = value[i+len(",default="):], true
+		value = value[:i]
+	}
+
+	parts := strings.Split(value, ",")
+	tag.key = parts[0]
+
+	for _, option := range parts[1:] {
+		if option == "required" {
+			tag.required = true
+		}
+	}
+
+	return tag, tag.key != ""
+}
+
+func setField(v reflect.Value, raw string) error {
+	if !v.CanSet() {
+		return fmt.Errorf("field can not be set")
+	}
+
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(raw)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(raw, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(raw, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(raw, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	default:
+		return fmt.Errorf("unsupported field type %s", v.Type())
+	}
+
+	return nil
+}
@@ -0,0 +1,115 @@
+package goawshelpers
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Unmarshal(t *testing.T) {
+	client := newFakeSSM()
+	client.seed("/dev/database/url", "postgres://localhost")
+	client.seed("/dev/port", "5432")
+	client.seed("/dev/debug", "true")
+	c := NewSSMConfigurationWithClient(client, "dev", "_")
+
+	var cfg struct {
+		DatabaseURL string  `ssm:"database_url"`
+		Port        int     `ssm:"port"`
+		Debug       bool    `ssm:"debug"`
+		Ratio       float64 `ssm:"ratio" default:"0.5"`
+		Ignored     string
+	}
+
+	assert.Nil(t, Unmarshal(c, &cfg))
+	assert.Equal(t, "postgres://localhost", cfg.DatabaseURL)
+	assert.Equal(t, 5432, cfg.Port)
+	assert.True(t, cfg.Debug)
+	assert.Equal(t, 0.5, cfg.Ratio)
+
+	var missing struct {
+		Port  int    `ssm:"port"`
+		Token string `ssm:"token"`
+		Name  string `ssm:"name"`
+	}
+
+	err := Unmarshal(c, &missing)
+	assert.Contains(t, fmt.Sprint(err), "missing required keys: token, name")
+
+	var invalid struct {
+		URL int `ssm:"database_url"`
+	}
+	assert.NotNil(t, Unmarshal(c, &invalid))
+	assert.NotNil(t, Unmarshal(c, invalid))
+}
+
+func Test_BindConfig(t *testing.T) {
+	client := newFakeSSM()
+	client.seed("/dev/db/url", "postgres://localhost")
+	client.seed("/dev/workers", "4")
+	client.seed("/dev/debug", "yes")
+	client.seed("/dev/ratio", "0.25")
+	c := NewSSMConfigurationWithClient(client, "dev", "_")
+
+	var cfg struct {
+		DatabaseURL string        `ssm:"db_url,required"`
+		Workers     int           `ssm:"workers"`
+		Ratio       float64       `ssm:"ratio"`
+		Timeout     time.Duration `ssm:"timeout,default=30s"`
+		Region      string        `ssm:"region"`
+	}
+
+	assert.Nil(t, BindConfig(c, &cfg))
+	assert.Equal(t, "postgres://localhost", cfg.DatabaseURL)
+	assert.Equal(t, 4, cfg.Workers)
+	assert.Equal(t, 0.25, cfg.Ratio)
+	assert.Equal(t, 30*time.Second, cfg.Timeout)
+	assert.Equal(t, "", cfg.Region)
+
+	var broken struct {
+		Token   string        `ssm:"token,required"`
+		Secret  string        `ssm:"secret,required"`
+		Debug   bool          `ssm:"debug"`
+		Timeout time.Duration `ssm:"timeout,default=soon"`
+	}
+
+	err := BindConfig(c, &broken)
+	var errs KeyErrors
+	assert.True(t, errors.As(err, &errs))
+	assert.Len(t, errs, 4)
+	assert.True(t, errors.Is(errs["token"], ErrParameterNotFound))
+	assert.True(t, errors.Is(errs["secret"], ErrParameterNotFound))
+	assert.Contains(t, fmt.Sprint(errs["debug"]), "Debug")
+	assert.Contains(t, fmt.Sprint(errs["timeout"]), "Timeout")
+
+	assert.NotNil(t, BindConfig(c, broken))
+}
+
+func Test_Unmarshal_TagOptions(t *testing.T) {
+	c := NewMemoryConfiguration(map[string]string{"interval": "1m"})
+
+	var cfg struct {
+		Interval time.Duration `ssm:"interval"`
+		Timeout  time.Duration `ssm:"timeout,default=5s"`
+		Hosts    string        `ssm:"hosts,default=a,b,c"`
+	}
+
+	assert.Nil(t, Unmarshal(c, &cfg))
+	assert.Equal(t, time.Minute, cfg.Interval)
+	assert.Equal(t, 5*time.Second, cfg.Timeout)
+	assert.Equal(t, "a,b,c", cfg.Hosts)
+}
+
+func Test_parseTag(t *testing.T) {
+	var cfg struct {
+		Hosts string `ssm:"hosts,required,default=a,b,required"`
+	}
+
+	tag, ok := parseTag(reflect.TypeOf(cfg).Field(0))
+	assert.True(t, ok)
+	assert.Equal(t, fieldTag{key: "hosts", required: true, def: "a,b,required", hasDefault: true}, tag)
+}
@@ -0,0 +1,42 @@
+package goawshelpers
+
+import (
+	"errors"
+	"fmt"
+)
+
+// maxParameterNameLength is the SSM limit for a fully qualified parameter name
+const maxParameterNameLength = 1011
+
+// ErrInvalidKey is returned when a key can't be used as an SSM parameter name
+var ErrInvalidKey = errors.New("invalid key")
+
+// ValidateKey checks a key or parameter name against the SSM naming rules without calling AWS
+// Keys can't be empty, longer than 1011 characters and may only contain letters, numbers and . - _ /
+func ValidateKey(key string) error {
+	if key == "" {
+		return fmt.Errorf("key is empty - %w", ErrInvalidKey)
+	}
+
+	if len(key) > maxParameterNameLength {
+		return fmt.Errorf("key is %d characters long, the limit is %d - %w", len(key), maxParameterNameLength, ErrInvalidKey)
+	}
+
+	for _, r := range key {
+		if !isParameterNameRune(r) {
+			return fmt.Errorf("key %q contains %q, only letters, numbers and . - _ / are allowed - %w", key, r, ErrInvalidKey)
+		}
+	}
+
+	return nil
+}
+
+func isParameterNameRune(r rune) bool {
+	switch {
+	case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		return true
+	case r == '.', r == '-', r == '_', r == '/':
+		return true
+	}
+	return false
+}
@@ -0,0 +1,34 @@
+package goawshelpers
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ValidateKey(t *testing.T) {
+	assert.Nil(t, ValidateKey("/dev/service/db_host-1.2"))
+
+	for _, key := range []string{"", "db host", "db$host", "hello:world", strings.Repeat("a", 1012)} {
+		assert.True(t, errors.Is(ValidateKey(key), ErrInvalidKey), key)
+	}
+}
+
+func Test_SSMConfiguration_InvalidKey(t *testing.T) {
+	client := newFakeSSM()
+	c := NewSSMConfigurationWithClient(client, "dev", "_")
+
+	err := c.Set("db host", "value")
+	assert.True(t, errors.Is(err, ErrInvalidKey))
+	assert.Nil(t, client.lastPut)
+
+	_, err = c.Get("db host")
+	assert.True(t, errors.Is(err, ErrInvalidKey))
+	assert.Nil(t, client.lastGet)
+
+	client.seed("/dev/key", "value")
+	_, err = c.GetVersion("key", 1)
+	assert.Nil(t, err)
+}
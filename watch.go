@@ -0,0 +1,141 @@
+package goawshelpers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// watchBuffer is the number of unread events WatchChan keeps before dropping the oldest ones
+const watchBuffer = 64
+
+// ChangeType tells how a key changed between two polls
+type ChangeType string
+
+const (
+	ChangeAdded   ChangeType = "added"
+	ChangeUpdated ChangeType = "updated"
+	ChangeRemoved ChangeType = "removed"
+)
+
+// ChangeEvent is a change of a single key emitted by WatchChan, Old is empty for added keys and New for removed ones
+type ChangeEvent struct {
+	Key  string
+	Old  string
+	New  string
+	Type ChangeType
+}
+
+// Watch polls the environment every interval and calls onChange for each added, changed or removed key
+// Added keys are reported with an empty oldVal and removed keys with an empty newVal
+// The initial snapshot is taken before returning, polling continues in the background until ctx is done or Close is called
+// Failed polls are skipped and retried on the next interval
+func (c *SSMConfiguration) Watch(ctx context.Context, interval time.Duration, onChange func(key, oldVal, newVal string)) error {
+	snapshot, err := c.GetEnvironmentWithContext(ctx)
+
+	if err != nil {
+		return fmt.Errorf("error taking initial snapshot - %w", err)
+	}
+
+	go c.poll(ctx, interval, snapshot, func(old, current map[string]string) {
+		for _, key := range diffKeys(old, current) {
+			onChange(key, old[key], current[key])
+		}
+	})
+
+	return nil
+}
+
+// WatchChan is the same as Watch but streams the changes to a channel, which is closed once ctx is done or Close is called
+// The poller never blocks on a slow consumer: up to 64 events are buffered, after that the oldest unread ones are dropped
+func (c *SSMConfiguration) WatchChan(ctx context.Context, interval time.Duration) (<-chan ChangeEvent, error) {
+	snapshot, err := c.GetEnvironmentWithContext(ctx)
+
+	if err != nil {
+		return nil, fmt.Errorf("error taking initial snapshot - %w", err)
+	}
+
+	events := make(chan ChangeEvent, watchBuffer)
+
+	go func() {
+		defer close(events)
+
+		c.poll(ctx, interval, snapshot, func(old, current map[string]string) {
+			for _, key := range diffKeys(old, current) {
+				event := ChangeEvent{Key: key, Old: old[key], New: current[key], Type: ChangeUpdated}
+
+				if _, ok := old[key]; !ok {
+					event.Type = ChangeAdded
+				} else if _, ok := current[key]; !ok {
+					event.Type = ChangeRemoved
+				}
+
+				sendDropOldest(events, event)
+			}
+		})
+	}()
+
+	return events, nil
+}
+
+// poll reads the environment every interval and passes the previous and the current values to emit until ctx is done or c is closed
+func (c *SSMConfiguration) poll(ctx context.Context, interval time.Duration, snapshot map[string]string, emit func(old, current map[string]string)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-c.closed:
+			return
+		case <-ticker.C:
+			current, err := c.GetEnvironmentWithContext(ctx)
+
+			if err != nil {
+				continue
+			}
+
+			emit(snapshot, current)
+			snapshot = current
+		}
+	}
+}
+
+// sendDropOldest sends event without blocking, making room by discarding the oldest buffered event
+func sendDropOldest(events chan ChangeEvent, event ChangeEvent) {
+	for {
+		select {
+		case events <- event:
+			return
+		default:
+		}
+
+		select {
+		case <-events:
+		default:
+		}
+	}
+}
+
+// diffKeys returns the sorted keys that differ between old and new
+func diffKeys(old, new map[string]string) []string {
+	var keys []string
+
+	for key, oldVal := range old {
+		if newVal, ok := new[key]; !ok || newVal != oldVal {
+			keys = append(keys, key)
+		}
+	}
+
+	for key := range new {
+		if _, ok := old[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
@@ -0,0 +1,131 @@
+package goawshelpers
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_SSMConfiguration_Watch(t *testing.T) {
+	client := newFakeSSM()
+	client.seed("/dev/flag", "off")
+	client.seed("/dev/removed", "value")
+	c := NewSSMConfigurationWithClient(client, "dev", "_")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var mu sync.Mutex
+	changes := make(map[string][2]string)
+
+	err := c.Watch(ctx, 10*time.Millisecond, func(key, oldVal, newVal string) {
+		mu.Lock()
+		defer mu.Unlock()
+		changes[key] = [2]string{oldVal, newVal}
+	})
+	assert.Nil(t, err)
+
+	client.seed("/dev/flag", "on")
+	client.seed("/dev/added", "new")
+	client.mu.Lock()
+	delete(client.params, "/dev/removed")
+	client.mu.Unlock()
+
+	assert.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(changes) == 3
+	}, time.Second, 5*time.Millisecond)
+
+	mu.Lock()
+	assert.Equal(t, [2]string{"off", "on"}, changes["flag"])
+	assert.Equal(t, [2]string{"", "new"}, changes["added"])
+	assert.Equal(t, [2]string{"value", ""}, changes["removed"])
+	mu.Unlock()
+}
+
+func Test_diffKeys(t *testing.T) {
+	old := map[string]string{"a": "1", "b": "2", "c": "3"}
+	new := map[string]string{"a": "1", "b": "20", "d": "4"}
+
+	assert.Equal(t, []string{"b", "c", "d"}, diffKeys(old, new))
+	assert.Empty(t, diffKeys(old, old))
+}
+
+func Test_SSMConfiguration_WatchChan(t *testing.T) {
+	client := newFakeSSM()
+	client.seed("/dev/flag", "off")
+	client.seed("/dev/removed", "value")
+	c := NewSSMConfigurationWithClient(client, "dev", "_")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events, err := c.WatchChan(ctx, 10*time.Millisecond)
+	assert.Nil(t, err)
+
+	client.seed("/dev/flag", "on")
+	client.seed("/dev/added", "new")
+	client.mu.Lock()
+	delete(client.params, "/dev/removed")
+	client.mu.Unlock()
+
+	changes := make(map[string]ChangeEvent)
+	for len(changes) < 3 {
+		select {
+		case event := <-events:
+			changes[event.Key] = event
+		case <-time.After(time.Second):
+			t.Fatal("timed out waiting for changes")
+		}
+	}
+
+	assert.Equal(t, ChangeEvent{Key: "flag", Old: "off", New: "on", Type: ChangeUpdated}, changes["flag"])
+	assert.Equal(t, ChangeEvent{Key: "added", New: "new", Type: ChangeAdded}, changes["added"])
+	assert.Equal(t, ChangeEvent{Key: "removed", Old: "value", Type: ChangeRemoved}, changes["removed"])
+
+	cancel()
+	assert.Eventually(t, func() bool {
+		_, open := <-events
+		return !open
+	}, time.Second, 5*time.Millisecond)
+
+	client.err = errors.New("denied")
+	_, err = c.WatchChan(context.Background(), time.Second)
+	assert.NotNil(t, err)
+}
+
+func Test_sendDropOldest(t *testing.T) {
+	events := make(chan ChangeEvent, 2)
+
+	sendDropOldest(events, ChangeEvent{Key: "a"})
+	sendDropOldest(events, ChangeEvent{Key: "b"})
+	sendDropOldest(events, ChangeEvent{Key: "c"})
+
+	assert.Equal(t, "b", (<-events).Key)
+	assert.Equal(t, "c", (<-events).Key)
+}
+
+func Test_SSMConfiguration_Close(t *testing.T) {
+	client := newFakeSSM()
+	c := NewSSMConfigurationWithClient(client, "dev", "_")
+
+	events, err := c.WatchChan(context.Background(), 10*time.Millisecond)
+	assert.Nil(t, err)
+
+	assert.Nil(t, c.Close())
+	assert.Nil(t, c.Close())
+
+	select {
+	case _, open := <-events:
+		assert.False(t, open)
+	case <-time.After(time.Second):
+		t.Fatal("WatchChan was not stopped by Close")
+	}
+
+	assert.Nil(t, (&SSMConfiguration{}).Close())
+}